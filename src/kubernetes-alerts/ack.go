@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+var (
+	ErrCheckPassing = errors.New("check is passing")
+	ErrNotLeader    = errors.New("not the leader")
+)
+
+// Acknowledgement records who is working on a failing check. It is stored with the check and
+// cleared when the check changes status.
+type Acknowledgement struct {
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+func (a *Acknowledgement) String() string {
+	ack := "acknowledged by " + a.By
+	if a.Comment != "" {
+		ack += ": " + a.Comment
+	}
+	return ack
+}
+
+// acknowledgeCheck stores the acknowledgement with the check under key and notifies it. The
+// check is updated atomically so a concurrent status change is not overwritten. A standby
+// replica does not send notifications, so it refuses the acknowledgement with ErrNotLeader.
+func (n *NotifManager) acknowledgeCheck(key string, ack Acknowledgement) (KubeCheck, error) {
+	var check KubeCheck
+	if !n.running() {
+		return check, ErrNotLeader
+	}
+	kvpair, err := n.store.Get(key)
+	if err != nil {
+		return check, err
+	}
+	if err := json.Unmarshal(kvpair.Value, &check); err != nil {
+		return check, err
+	}
+	if check.Status == CheckStatusPass {
+		return check, ErrCheckPassing
+	}
+
+	check.Acknowledgement = &ack
+	data, err := json.Marshal(&check)
+	if err != nil {
+		return check, err
+	}
+	if _, _, err := n.store.AtomicPut(key, data, kvpair, nil); err != nil {
+		return check, err
+	}
+	logrus.Infof("check %s %s", check.Name, ack.String())
+	n.addNotification(check)
+	return check, nil
+}
+
+// acknowledge acknowledges a check by its key, e.g.
+// POST /api/acks/node/node-ready/node-1 {"by": "jane", "comment": "replacing the disk"}
+func (h *HttpServer) acknowledge(w http.ResponseWriter, r *http.Request) {
+	if !h.allowApi(w, r, "POST") {
+		return
+	}
+	key, ok := checkKeyFromPath(strings.TrimPrefix(r.URL.Path, "/api/acks/"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "expected /api/acks/<group>/<type>/<name>")
+		return
+	}
+	var ack Acknowledgement
+	if err := json.NewDecoder(r.Body).Decode(&ack); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid acknowledgement: "+err.Error())
+		return
+	}
+	if ack.By == "" {
+		writeError(w, http.StatusBadRequest, "an acknowledgement needs a user")
+		return
+	}
+	ack.At = time.Now()
+
+	check, err := h.NotifManager.acknowledgeCheck(key, ack)
+	switch err {
+	case nil:
+		writeJson(w, http.StatusOK, check)
+	case store.ErrKeyNotFound:
+		writeError(w, http.StatusNotFound, "check not found")
+	case ErrCheckPassing:
+		writeError(w, http.StatusConflict, "check is passing")
+	case store.ErrKeyModified:
+		writeError(w, http.StatusConflict, "check has changed, try again")
+	case ErrNotLeader:
+		writeError(w, http.StatusServiceUnavailable, "not the leader, try again")
+	default:
+		logrus.WithError(err).Error("unable to acknowledge check")
+		writeError(w, http.StatusInternalServerError, "unable to acknowledge check")
+	}
+}
+
+// checkKeyFromPath returns the check key of a <group>/<type>/<name> path.
+func checkKeyFromPath(path string) (string, bool) {
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return checkKey(KubeCheckGroup(parts[0]), KubeCheckType(parts[1]), parts[2]), true
+}
+
+type slackActionPayload struct {
+	Token      string `json:"token"`
+	CallbackId string `json:"callback_id"`
+	Actions    []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"actions"`
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+type slackActionResponse struct {
+	Text            string `json:"text"`
+	ResponseType    string `json:"response_type"`
+	ReplaceOriginal bool   `json:"replace_original"`
+}
+
+// slackActions handles the buttons of the Slack notifications. The request must carry the
+// verification token of a Slack notifier instead of the API token.
+func (h *HttpServer) slackActions(w http.ResponseWriter, r *http.Request) {
+	if !h.allowRequest(w, r, "POST") {
+		return
+	}
+	var payload slackActionPayload
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &payload); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	if !h.NotifManager.slackTokenValid(payload.Token) {
+		writeError(w, http.StatusUnauthorized, "invalid verification token")
+		return
+	}
+	if payload.CallbackId != SlackAckCallback || len(payload.Actions) == 0 {
+		writeError(w, http.StatusBadRequest, "unknown action")
+		return
+	}
+	// only the keys of checks can be acknowledged, not any KV key
+	key, ok := checkKeyFromPath(strings.TrimPrefix(payload.Actions[0].Value, "kube-alerts/"))
+	if !ok || key != payload.Actions[0].Value {
+		writeError(w, http.StatusBadRequest, "unknown action")
+		return
+	}
+	ack := Acknowledgement{By: payload.User.Name, At: time.Now()}
+	text := fmt.Sprintf(":white_check_mark: %s acknowledged by @%s", key, payload.User.Name)
+	switch _, err := h.NotifManager.acknowledgeCheck(key, ack); err {
+	case nil:
+	case store.ErrKeyNotFound:
+		text = fmt.Sprintf("%s is no longer recorded", key)
+	case ErrCheckPassing:
+		text = fmt.Sprintf("%s is passing again", key)
+	case ErrNotLeader:
+		text = fmt.Sprintf("unable to acknowledge %s on a standby replica, try again", key)
+	default:
+		logrus.WithError(err).Error("unable to acknowledge check")
+		text = fmt.Sprintf("unable to acknowledge %s, try again", key)
+	}
+	writeJson(w, http.StatusOK, slackActionResponse{Text: text, ResponseType: "in_channel"})
+}
+
+// slackTokenValid returns true if the token is the verification token of a Slack notifier.
+func (n *NotifManager) slackTokenValid(token string) bool {
+	n.settingsLock.RLock()
+	defer n.settingsLock.RUnlock()
+	for _, notifier := range n.Notifiers {
+		slack, ok := notifier.(*SlackNotifier)
+		if ok && slack.VerificationToken != "" && subtle.ConstantTimeCompare([]byte(slack.VerificationToken), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"crypto/tls"
@@ -16,6 +18,7 @@ import (
 
 type ApiClient struct {
 	*http.Client
+	name                 string
 	apiBaseUrl           string
 	certificateAuthority string
 	clientCertificate    string
@@ -24,6 +27,27 @@ type ApiClient struct {
 	tokenFile            string
 }
 
+// ApiError is returned when a request is answered with an unexpected status code.
+type ApiError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *ApiError) Error() string {
+	return e.Status
+}
+
+type streamBody struct {
+	io.ReadCloser
+	done chan bool
+	once sync.Once
+}
+
+func (s *streamBody) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.ReadCloser.Close()
+}
+
 func (a *ApiClient) prepareClient() error {
 	var cacert *x509.CertPool
 	if a.certificateAuthority != "" {
@@ -74,6 +98,30 @@ func (a *ApiClient) prepareClient() error {
 	return nil
 }
 
+// close closes the idle connections of the client.
+func (a *ApiClient) close() {
+	if a.Client == nil {
+		return
+	}
+	transport := a.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if t, ok := transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// do sends the request, counting the failed requests in the api error metrics. Gone is not
+// counted as it only means a watch has to be restarted.
+func (a *ApiClient) do(req *http.Request) (*http.Response, error) {
+	res, err := a.Do(req)
+	if err != nil || (res.StatusCode >= 400 && res.StatusCode != http.StatusGone) {
+		metrics.apiError(a.name)
+	}
+	return res, err
+}
+
 func (a *ApiClient) GetRequest(path string, resData interface{}) error {
 	endpoint := a.apiBaseUrl + path
 	logrus.Debugf("GET request to: %s", endpoint)
@@ -84,7 +132,7 @@ func (a *ApiClient) GetRequest(path string, resData interface{}) error {
 	if a.token != "" {
 		req.Header.Add("Authorization", "Bearer "+a.token)
 	}
-	res, err := a.Do(req)
+	res, err := a.do(req)
 	if err != nil {
 		return err
 	}
@@ -101,6 +149,59 @@ func (a *ApiClient) GetRequest(path string, resData interface{}) error {
 	return nil
 }
 
+// GetRawRequest sends a GET request to an absolute url and returns the response status code and body.
+func (a *ApiClient) GetRawRequest(endpoint string) (int, []byte, error) {
+	logrus.Debugf("GET request to: %s", endpoint)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if a.token != "" {
+		req.Header.Add("Authorization", "Bearer "+a.token)
+	}
+	res, err := a.do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
+
+// StreamRequest sends a GET request and returns the response body to be read as a stream.
+// The body is closed once stop is closed.
+func (a *ApiClient) StreamRequest(path string, stop <-chan bool) (io.ReadCloser, error) {
+	endpoint := a.apiBaseUrl + path
+	logrus.Debugf("GET stream request to: %s", endpoint)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.token != "" {
+		req.Header.Add("Authorization", "Bearer "+a.token)
+	}
+	res, err := a.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, &ApiError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	done := make(chan bool)
+	go func() {
+		select {
+		case <-stop:
+			res.Body.Close()
+		case <-done:
+		}
+	}()
+	return &streamBody{ReadCloser: res.Body, done: done}, nil
+}
+
 func (a *ApiClient) PostRequest(path string, data io.Reader) error {
 	endpoint := a.apiBaseUrl + path
 	logrus.Debugf("POST request to: %s", endpoint)
@@ -111,7 +212,7 @@ func (a *ApiClient) PostRequest(path string, data io.Reader) error {
 	if a.token != "" {
 		req.Header.Add("Authorization", "Bearer "+a.token)
 	}
-	res, err := a.Do(req)
+	res, err := a.do(req)
 	if err != nil {
 		return err
 	}
@@ -135,7 +236,7 @@ func (a *ApiClient) PutRequest(path, data string) error {
 	if a.token != "" {
 		req.Header.Add("Authorization", "Bearer "+a.token)
 	}
-	res, err := a.Do(req)
+	res, err := a.do(req)
 	if err != nil {
 		return err
 	}
@@ -146,3 +247,48 @@ func (a *ApiClient) PutRequest(path, data string) error {
 	}
 	return errors.New(res.Status)
 }
+
+// JsonRequest sends data, if not nil, encoded in JSON and decodes the response into resData, if
+// not nil. An ApiError is returned when the response is not successful.
+func (a *ApiClient) JsonRequest(method, path string, data, resData interface{}) error {
+	endpoint := a.apiBaseUrl + path
+	logrus.Debugf("%s request to: %s", method, endpoint)
+	var body io.Reader
+	if data != nil {
+		reqBody, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(reqBody)
+	}
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Add("Authorization", "Bearer "+a.token)
+	}
+	res, err := a.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &ApiError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	if resData == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resData)
+}
+
+// sameConnection returns true if the other client connects to the same API with the same credentials.
+func (a *ApiClient) sameConnection(other *ApiClient) bool {
+	return a.apiBaseUrl == other.apiBaseUrl &&
+		a.certificateAuthority == other.certificateAuthority &&
+		a.clientCertificate == other.clientCertificate &&
+		a.clientKey == other.clientKey &&
+		a.token == other.token &&
+		a.tokenFile == other.tokenFile
+}
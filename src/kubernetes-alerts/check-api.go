@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// CheckFilter selects checks by group, type, status and node. Each field holds a comma
+// separated list of accepted values, empty accepts every value.
+type CheckFilter struct {
+	Groups   []string
+	Types    []string
+	Statuses []string
+	Nodes    []string
+}
+
+func newCheckFilter(r *http.Request) CheckFilter {
+	query := r.URL.Query()
+	return CheckFilter{
+		Groups:   splitList(query.Get("group")),
+		Types:    splitList(query.Get("type")),
+		Statuses: splitList(query.Get("status")),
+		Nodes:    splitList(query.Get("node")),
+	}
+}
+
+func (f CheckFilter) matches(check KubeCheck) bool {
+	return matchesAny(f.Groups, string(check.CheckGroup)) &&
+		matchesAny(f.Types, string(check.CheckType)) &&
+		matchesAny(f.Statuses, string(check.Status)) &&
+		matchesAny(f.Nodes, check.Node)
+}
+
+func matchesAny(values []string, value string) bool {
+	return len(values) == 0 || containsString(value, values)
+}
+
+// listChecks serves the checks recorded in the KV store, e.g.
+// GET /api/checks?group=node&status=warn,fail
+func (h *HttpServer) listChecks(w http.ResponseWriter, r *http.Request) {
+	if !h.allowApi(w, r, "GET") {
+		return
+	}
+	checks, err := h.KVClient.listChecks()
+	if err != nil {
+		logrus.WithError(err).Error("unable to list checks")
+		writeError(w, http.StatusInternalServerError, "unable to list checks")
+		return
+	}
+	filter := newCheckFilter(r)
+	matched := make([]KubeCheck, 0, len(checks))
+	for _, check := range checks {
+		if filter.matches(check) {
+			matched = append(matched, check)
+		}
+	}
+	writeJson(w, http.StatusOK, matched)
+}
+
+// getCheck serves a single check by its key, e.g. GET /api/checks/node/node-ready/node-1
+func (h *HttpServer) getCheck(w http.ResponseWriter, r *http.Request) {
+	if !h.allowApi(w, r, "GET") {
+		return
+	}
+	key, ok := checkKeyFromPath(strings.TrimPrefix(r.URL.Path, "/api/checks/"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "expected /api/checks/<group>/<type>/<name>")
+		return
+	}
+	var check KubeCheck
+	err := h.KVClient.getValue(key, &check)
+	if err == store.ErrKeyNotFound {
+		writeError(w, http.StatusNotFound, "check not found")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("unable to get check")
+		writeError(w, http.StatusInternalServerError, "unable to get check")
+		return
+	}
+	writeJson(w, http.StatusOK, check)
+}
+
+// allowApi writes an error and returns false if the method is not allowed, the KV store is not
+// connected yet or the request is not authorized.
+func (h *HttpServer) allowApi(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	if !h.allowRequest(w, r, methods...) {
+		return false
+	}
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="kube-alerts"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	return true
+}
+
+// authorized returns true if the request carries the API token. Without an API token only the
+// requests from localhost are authorized, e.g. through kubectl port-forward.
+func (h *HttpServer) authorized(r *http.Request) bool {
+	token := h.apiToken()
+	if token != "" {
+		return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// allowRequest writes an error and returns false if the method is not allowed or the KV store
+// is not connected yet.
+func (h *HttpServer) allowRequest(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	if !containsString(r.Method, methods) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	if !h.isReady() {
+		writeError(w, http.StatusServiceUnavailable, "not ready")
+		return false
+	}
+	return true
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logrus.WithError(err).Error("unable to marshall response")
+		http.Error(w, "unable to marshall response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// CheckProcessor records check results in the KV store and queues notifications on state changes.
+type CheckProcessor struct {
+	*KVClient
+	*NotifManager
+	FlapWindow      time.Duration
+	FlapTransitions int
+	NotifyRemoved   bool
+	History         *CheckHistory
+	settingsLock    sync.RWMutex
+}
+
+// reload replaces the flap detection and removed check settings with the updated settings.
+func (c *CheckProcessor) reload(updated *CheckProcessor) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+	c.FlapWindow = updated.FlapWindow
+	c.FlapTransitions = updated.FlapTransitions
+	c.NotifyRemoved = updated.NotifyRemoved
+}
+
+// processCheck records the check and notifies it when it is new and failing or its status has
+// changed. New checks that are not passing and status changes are added to the history. Saving
+// a new status clears the acknowledgement of the previous one. A check changing status
+// FlapTransitions times within the FlapWindow is flapping: it is notified once, and once more
+// when it has been stable for the FlapWindow.
+func (c *CheckProcessor) processCheck(check KubeCheck) {
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	metrics.checkResult(check)
+	exists, err := c.checkExists(check)
+	if err != nil {
+		logrus.WithError(err).Error("unable to determine if check exists or not")
+		return
+	}
+	if !exists {
+		logrus.Infof("check %s is not in the record. recoding now", check.Name)
+		err := c.saveCheck(check)
+		if err != nil {
+			logrus.WithError(err).Warnf("Unable to save check")
+			return
+		}
+		if check.Status != CheckStatusPass {
+			c.History.record(check, "")
+		}
+		if check.Status == CheckStatusFail {
+			logrus.Info("check %s is new and failing, will notify", check.Name)
+			c.addNotification(check)
+		}
+	} else {
+		oldCheck, err := c.getCheck(check.CheckGroup, check.CheckType, check.Name)
+		if err != nil {
+			logrus.WithError(err).Warnf("unable to get previous check, can't proceed")
+			return
+		}
+		logrus.Printf("old: %s, new: %s", oldCheck.Status, check.Status)
+		if check.Status != oldCheck.Status {
+			logrus.Debugf("check %s status has changed, will notify", check.Name)
+			logrus.Debugf("status for %s:%s:%s has changed.", check.CheckGroup, check.CheckType, check.Name)
+			check.Flapping = oldCheck.Flapping
+			transitions := 0
+			if c.FlapTransitions > 0 {
+				if transitions, err = c.recordTransition(check, time.Now()); err != nil {
+					logrus.WithError(err).Warnf("unable to record transition of check %s", check.Name)
+				}
+				if transitions >= c.FlapTransitions {
+					check.Flapping = true
+				}
+			}
+			err := c.saveCheck(check)
+			if err != nil {
+				logrus.WithError(err).Warnf("Unable to save")
+				return
+			}
+			c.History.record(check, oldCheck.Status)
+			switch {
+			case check.Flapping && oldCheck.Flapping:
+				logrus.Debugf("check %s is still flapping, not notifying", check.Name)
+			case check.Flapping:
+				logrus.Infof("check %s is flapping, will notify", check.Name)
+				check.Message = flappingMessage(check, transitions, c.FlapWindow)
+				c.addNotification(check)
+			default:
+				logrus.Infof("check %s is failing, will notify", check.Name)
+				c.addNotification(check)
+			}
+		} else if oldCheck.Flapping {
+			c.processFlapping(oldCheck)
+		} else {
+			logrus.Debug("nothing has changed.")
+		}
+	}
+}
+
+// statusTracker records how long a check has been in its current status, for checks
+// that have no transition time of their own.
+type statusTracker struct {
+	sync.Mutex
+	states map[string]trackedStatus
+}
+
+type trackedStatus struct {
+	status CheckStatus
+	since  time.Time
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{states: make(map[string]trackedStatus)}
+}
+
+// held returns true once key has been in status for at least threshold.
+func (t *statusTracker) held(key string, status CheckStatus, threshold time.Duration) bool {
+	t.Lock()
+	defer t.Unlock()
+	state, ok := t.states[key]
+	if !ok || state.status != status {
+		state = trackedStatus{status: status, since: time.Now()}
+		t.states[key] = state
+	}
+	return time.Since(state.since) >= threshold
+}
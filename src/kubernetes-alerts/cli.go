@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const DefaultServer = "http://localhost:9000"
+
+// commands are run against the API of a running kube-alerts instead of starting one,
+// e.g. kube-alerts silence list
+var commands = map[string]func(args []string) error{
+	"silence": silenceCommand,
+	"ack":     ackCommand,
+	"history": historyCommand,
+}
+
+// runCommand runs a command and returns the exit code.
+func runCommand(name string, args []string) int {
+	err := commands[name](args)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kube-alerts %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// CliClient sends the requests of the commands to the API of kube-alerts.
+type CliClient struct {
+	*http.Client
+	Server string
+	Token  string
+}
+
+// newCliFlagSet returns the flag set of a command, with the -server flag of its client.
+func newCliFlagSet(name string) (*flag.FlagSet, *CliClient) {
+	client := &CliClient{Client: &http.Client{Timeout: 30 * time.Second}}
+	server := os.Getenv("KUBE_ALERTS_SERVER")
+	if server == "" {
+		server = DefaultServer
+	}
+	fs := flag.NewFlagSet("kube-alerts "+name, flag.ContinueOnError)
+	fs.StringVar(&client.Server, "server", server, "kube-alerts API address, defaults to $KUBE_ALERTS_SERVER")
+	fs.StringVar(&client.Token, "token", os.Getenv(envName("api-token")), "kube-alerts API token, defaults to $KUBE_ALERTS_API_TOKEN")
+	return fs, client
+}
+
+// request sends the request body as JSON and decodes the JSON response into result.
+func (c *CliClient) request(method, path string, body, result interface{}) error {
+	var data io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		data = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Server, "/")+path, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(res.Body).Decode(&apiError) == nil && apiError.Error != "" {
+			return errors.New(apiError.Error)
+		}
+		return errors.New(res.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func silenceCommand(args []string) error {
+	usage := errors.New("expected add, list or expire, e.g. kube-alerts silence add -node node-1 -duration 2h -comment upgrade")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "add":
+		return silenceAdd(args[1:])
+	case "list":
+		return silenceList(args[1:])
+	case "expire":
+		return silenceExpire(args[1:])
+	default:
+		return usage
+	}
+}
+
+func silenceAdd(args []string) error {
+	fs, client := newCliFlagSet("silence add")
+	var silence Silence
+	var labels []string
+	var start string
+	fs.Var(&stringListValue{&silence.Nodes}, "node", "comma separated nodes to silence")
+	fs.Var(&stringListValue{&silence.Groups}, "group", "comma separated check groups to silence")
+	fs.Var(&stringListValue{&silence.Types}, "type", "comma separated check types to silence")
+	fs.Var(&repeatedValue{&labels}, "label", "label (name=value) of the checks to silence (repeatable)")
+	fs.StringVar(&start, "start", "", "start of the silence (RFC3339), defaults to now")
+	duration := fs.Duration("duration", time.Hour, "duration of the silence")
+	fs.StringVar(&silence.CreatedBy, "created-by", os.Getenv("USER"), "creator of the silence")
+	fs.StringVar(&silence.Comment, "comment", "", "reason for the silence")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	silence.Start = time.Now()
+	if start != "" {
+		var err error
+		if silence.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return fmt.Errorf("invalid -start: %v", err)
+		}
+	}
+	silence.End = silence.Start.Add(*duration)
+	for _, label := range labels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid -label %q, expected name=value", label)
+		}
+		if silence.Labels == nil {
+			silence.Labels = make(map[string]string)
+		}
+		silence.Labels[parts[0]] = parts[1]
+	}
+
+	var created Silence
+	if err := client.request("POST", "/api/silences", silence, &created); err != nil {
+		return err
+	}
+	fmt.Printf("silence %s created until %s\n", created.ID, created.End.Format(time.RFC3339))
+	return nil
+}
+
+func silenceList(args []string) error {
+	fs, client := newCliFlagSet("silence list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var silences []Silence
+	if err := client.request("GET", "/api/silences", nil, &silences); err != nil {
+		return err
+	}
+	w := newTabWriter()
+	fmt.Fprintln(w, "ID\tSTART\tEND\tCREATED BY\tMATCHERS\tCOMMENT")
+	for _, silence := range silences {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", silence.ID, silence.Start.Format(time.RFC3339), silence.End.Format(time.RFC3339),
+			silence.CreatedBy, silenceMatchers(silence), silence.Comment)
+	}
+	return w.Flush()
+}
+
+func silenceExpire(args []string) error {
+	fs, client := newCliFlagSet("silence expire")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected the id of the silence to expire")
+	}
+	if err := client.request("DELETE", "/api/silences/"+fs.Arg(0), nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("silence %s expired\n", fs.Arg(0))
+	return nil
+}
+
+func ackCommand(args []string) error {
+	fs, client := newCliFlagSet("ack")
+	var ack Acknowledgement
+	fs.StringVar(&ack.By, "by", os.Getenv("USER"), "user acknowledging the check")
+	fs.StringVar(&ack.Comment, "comment", "", "comment on the acknowledgement")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected the <group>/<type>/<name> of the check to acknowledge, e.g. kube-alerts ack node/node-ready/node-1")
+	}
+	var check KubeCheck
+	if err := client.request("POST", "/api/acks/"+strings.TrimPrefix(fs.Arg(0), "kube-alerts/"), ack, &check); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s\n", check.Name, check.Acknowledgement)
+	return nil
+}
+
+func historyCommand(args []string) error {
+	fs, client := newCliFlagSet("history")
+	query := url.Values{}
+	fs.Var(&queryValue{query, "group"}, "group", "comma separated check groups to list")
+	fs.Var(&queryValue{query, "type"}, "type", "comma separated check types to list")
+	fs.Var(&queryValue{query, "status"}, "status", "comma separated new statuses to list")
+	fs.Var(&queryValue{query, "node"}, "node", "comma separated nodes to list")
+	fs.Var(&queryValue{query, "since"}, "since", "start of the time range, RFC3339 or a duration before now, e.g. 24h")
+	fs.Var(&queryValue{query, "until"}, "until", "end of the time range, RFC3339 or a duration before now")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := "/api/history"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path += "/" + strings.TrimPrefix(fs.Arg(0), "kube-alerts/")
+	default:
+		return errors.New("expected the <group>/<type>/<name> of a single check, e.g. kube-alerts history -since 168h node/node-ready/node-1")
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var entries []HistoryEntry
+	if err := client.request("GET", path, nil, &entries); err != nil {
+		return err
+	}
+	w := newTabWriter()
+	fmt.Fprintln(w, "TIME\tCHECK\tOLD\tNEW\tMESSAGE")
+	for _, entry := range entries {
+		oldStatus := entry.OldStatus
+		if oldStatus == "" {
+			oldStatus = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Timestamp.Format(time.RFC3339), entry.Key, oldStatus, entry.NewStatus, entry.Message)
+	}
+	return w.Flush()
+}
+
+// queryValue is a flag setting a query parameter.
+type queryValue struct {
+	query url.Values
+	name  string
+}
+
+func (v *queryValue) String() string {
+	if v.query == nil {
+		return ""
+	}
+	return v.query.Get(v.name)
+}
+
+func (v *queryValue) Set(value string) error {
+	v.query.Set(v.name, value)
+	return nil
+}
+
+func silenceMatchers(silence Silence) string {
+	matchers := make([]string, 0)
+	if len(silence.Nodes) > 0 {
+		matchers = append(matchers, "nodes="+strings.Join(silence.Nodes, ","))
+	}
+	if len(silence.Groups) > 0 {
+		matchers = append(matchers, "groups="+strings.Join(silence.Groups, ","))
+	}
+	if len(silence.Types) > 0 {
+		matchers = append(matchers, "types="+strings.Join(silence.Types, ","))
+	}
+	if len(silence.Labels) > 0 {
+		matchers = append(matchers, "labels="+joinMap(silence.Labels, "="))
+	}
+	return strings.Join(matchers, ";")
+}
+
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	ConditionTypeHealthy = "Healthy"
+
+	ClusterCheckApiServer = "healthz"
+	// ClusterCheckNodes names the checks of the cluster nodes, told apart by their check type
+	ClusterCheckNodes = "nodes"
+)
+
+type ClusterChecker struct {
+	*KubernetesApi
+	*CheckProcessor
+	Enabled         bool
+	RunWaitGroup    sync.WaitGroup
+	CheckInterval   time.Duration
+	Threshold       time.Duration
+	MinReadyPercent float64
+	MinNodes        int
+	stopChannel     chan bool
+	settingsLock    sync.RWMutex
+
+	tracker *statusTracker
+}
+
+func (c *ClusterChecker) start() {
+	logrus.Info("Starting Cluster Checker...")
+	c.RunWaitGroup.Add(1)
+	metrics.checkerStarted(string(CheckGroupCluster))
+	c.stopChannel = make(chan bool)
+	c.tracker = newStatusTracker()
+	go c.run(c.stopChannel)
+}
+
+func (c *ClusterChecker) stop() {
+	close(c.stopChannel)
+}
+
+// run checks the API server and the components every check interval until stop is closed.
+func (c *ClusterChecker) run(stop <-chan bool) {
+	defer c.RunWaitGroup.Done()
+	running := true
+	for running {
+		select {
+		case <-time.After(c.checkInterval()):
+			// the cluster checks may have been disabled while waiting for the lock
+			c.settingsLock.RLock()
+			if !stopped(stop) {
+				c.processClusterCheck()
+			}
+			c.settingsLock.RUnlock()
+		case <-stop:
+			running = false
+		}
+	}
+}
+
+func (c *ClusterChecker) enabled() bool {
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	return c.Enabled
+}
+
+func (c *ClusterChecker) checkInterval() time.Duration {
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	return c.CheckInterval
+}
+
+// reload replaces the thresholds and intervals of the cluster checks. The checker is started or
+// stopped with -enable-cluster-checks, unless the services are stopped, e.g. on a standby.
+func (c *ClusterChecker) reload(updated *ClusterChecker, running bool) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+	wasEnabled := c.Enabled
+	c.Enabled = updated.Enabled
+	c.CheckInterval = updated.CheckInterval
+	c.Threshold = updated.Threshold
+	c.MinReadyPercent = updated.MinReadyPercent
+	c.MinNodes = updated.MinNodes
+
+	switch {
+	case !running:
+	case c.Enabled && !wasEnabled:
+		c.start()
+	case !c.Enabled && wasEnabled:
+		logrus.Info("Stopping Cluster Checker...")
+		c.stop()
+	}
+}
+
+func (c *ClusterChecker) processClusterCheck() {
+	metrics.checkCycle(string(CheckGroupCluster))
+	logrus.Debug("Running Cluster Checks...")
+	c.processApiServer()
+	c.processComponentStatuses()
+}
+
+func (c *ClusterChecker) processApiServer() {
+	logrus.Debug("Checking API Server Health...")
+	status := CheckStatusPass
+	message := "API server is healthy"
+	if err := c.Healthz(); err != nil {
+		status = CheckStatusFail
+		message = "API server is NOT healthy: " + err.Error()
+	}
+	c.processClusterCheckResult(ClusterCheckApiServer, CheckTypeApiServer, status, message)
+}
+
+func (c *ClusterChecker) processComponentStatuses() {
+	logrus.Debug("Checking Component Statuses...")
+	components, err := c.ComponentStatuses()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to retrieve component statuses.")
+		return
+	}
+	for _, component := range components {
+		name := component.Metadata.Name
+		status := CheckStatusFail
+		message := name + " is NOT healthy"
+		for _, condition := range component.Conditions {
+			if condition.Type != ConditionTypeHealthy {
+				continue
+			}
+			if condition.Status == "True" {
+				status = CheckStatusPass
+				message = name + " is healthy"
+			} else if condition.Error != "" {
+				message = fmt.Sprintf("%s is NOT healthy: %s", name, condition.Error)
+			}
+		}
+		c.processClusterCheckResult(name, CheckTypeComponent, status, message)
+	}
+}
+
+// processNodes runs the cluster checks using the node list fetched by the NodeChecker.
+func (c *ClusterChecker) processNodes(nodes []Node) {
+	if c == nil {
+		return
+	}
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	c.processNodesReady(nodes)
+	c.processNodeCount(nodes)
+}
+
+// processNodesReady checks the ratio of Ready nodes.
+func (c *ClusterChecker) processNodesReady(nodes []Node) {
+	if !c.Enabled || c.MinReadyPercent <= 0 || len(nodes) == 0 {
+		return
+	}
+	logrus.Debug("Checking Cluster Node Readiness...")
+	ready := 0
+	for _, node := range nodes {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == ConditionTypeReady && condition.Status == "True" {
+				ready++
+			}
+		}
+	}
+
+	percent := percentOf(uint64(ready), uint64(len(nodes)))
+	status := CheckStatusPass
+	if percent < c.MinReadyPercent {
+		status = CheckStatusFail
+	}
+	message := fmt.Sprintf("%d of %d nodes are Ready (%.0f%%)", ready, len(nodes), percent)
+	c.processClusterCheckResult(ClusterCheckNodes, CheckTypeNodesReady, status, message)
+}
+
+// processNodeCount warns when there are fewer nodes than the minimum.
+func (c *ClusterChecker) processNodeCount(nodes []Node) {
+	if !c.Enabled || c.MinNodes <= 0 {
+		return
+	}
+	logrus.Debug("Checking Cluster Node Count...")
+	status := CheckStatusPass
+	if len(nodes) < c.MinNodes {
+		status = CheckStatusWarn
+	}
+	message := fmt.Sprintf("%d nodes in the cluster, the minimum is %d", len(nodes), c.MinNodes)
+	c.processClusterCheckResult(ClusterCheckNodes, CheckTypeNodeCount, status, message)
+}
+
+func (c *ClusterChecker) processClusterCheckResult(name string, checkType KubeCheckType, status CheckStatus, message string) {
+	if !c.tracker.held(string(checkType)+"/"+name, status, c.Threshold) {
+		return
+	}
+
+	check := KubeCheck{
+		Name:       name,
+		CheckGroup: CheckGroupCluster,
+		CheckType:  checkType,
+		Status:     status,
+		Message:    message,
+		Timestamp:  time.Now(),
+	}
+
+	c.processCheck(check)
+}
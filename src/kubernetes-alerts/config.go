@@ -0,0 +1,376 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configFlags maps the settings of the config file to the flags they set.
+var configFlags = map[string]string{
+	"kubernetes.api":                   "k8s-api",
+	"kubernetes.certificate-authority": "k8s-certificate-authority",
+	"kubernetes.client-certificate":    "k8s-client-certificate",
+	"kubernetes.client-key":            "k8s-client-key",
+	"kubernetes.token":                 "k8s-token",
+	"kubernetes.token-file":            "k8s-token-file",
+
+	"heapster.api":                   "heapster-api",
+	"heapster.certificate-authority": "heapster-certificate-authority",
+	"heapster.client-certificate":    "heapster-client-certificate",
+	"heapster.client-key":            "heapster-client-key",
+	"heapster.token":                 "heapster-token",
+
+	"kv.backend":               "kv-backend",
+	"kv.addresses":             "kv-addresses",
+	"kv.certificate-authority": "kv-certificate-authority",
+	"kv.client-certificate":    "kv-client-certificate",
+	"kv.client-key":            "kv-client-key",
+	"kv.username":              "kv-username",
+	"kv.password":              "kv-password",
+	"kv.bucket":                "kv-bucket",
+	"kv.consul-token":          "kv-consul-token",
+	"kv.configmap":             "kv-configmap",
+
+	"checks.node.interval":          "node-check-interval",
+	"checks.node.threshold":         "node-check-threshold",
+	"checks.node.conditions":        "node-conditions",
+	"checks.node.condition-default": "node-condition-default",
+	"checks.node.cpu-warn":          "node-cpu-warn",
+	"checks.node.cpu-fail":          "node-cpu-fail",
+	"checks.node.mem-warn":          "node-mem-warn",
+	"checks.node.mem-fail":          "node-mem-fail",
+	"checks.node.membership":        "enable-node-membership-checks",
+
+	"checks.pod.enabled":        "enable-pod-checks",
+	"checks.pod.interval":       "pod-check-interval",
+	"checks.pod.threshold":      "pod-check-threshold",
+	"checks.pod.restart-limit":  "pod-restart-limit",
+	"checks.pod.restart-window": "pod-restart-window",
+
+	"checks.cluster.enabled":           "enable-cluster-checks",
+	"checks.cluster.interval":          "cluster-check-interval",
+	"checks.cluster.threshold":         "cluster-check-threshold",
+	"checks.cluster.min-ready-percent": "cluster-min-ready-percent",
+	"checks.cluster.min-nodes":         "cluster-min-nodes",
+
+	"notifications.interval":           "notification-interval",
+	"notifications.retry-interval":     "notification-retry-interval",
+	"notifications.max-retry-interval": "notification-max-retry-interval",
+	"notifications.max-attempts":       "notification-max-attempts",
+	"notifications.reminders.fail":     "reminder-fail-interval",
+	"notifications.reminders.warn":     "reminder-warn-interval",
+
+	"checks.notify-removed": "notify-removed",
+
+	"checks.flapping.window":      "flap-window",
+	"checks.flapping.transitions": "flap-transitions",
+
+	"leader.enabled": "leader-election",
+	"leader.id":      "leader-id",
+	"leader.lease":   "leader-lease",
+
+	"history.backend":   "history",
+	"history.file":      "history-file",
+	"history.retention": "history-retention",
+
+	"http.address":   "http-address",
+	"http.api-token": "api-token",
+
+	"log-level":             "log-level",
+	"config-watch-interval": "config-watch-interval",
+	"shutdown-timeout":      "shutdown-timeout",
+}
+
+// configSections are the config file sections holding other settings.
+var configSections = []string{"kubernetes", "heapster", "kv", "checks", "checks.node", "checks.pod", "checks.cluster", "checks.flapping", "notifications", "notifications.reminders", "leader", "history", "http"}
+
+// configMapSeparators are the separators used to turn a config map into the value of a flag.
+var configMapSeparators = map[string]string{
+	"node-conditions": "=",
+	"webhook-headers": ":",
+}
+
+type Config map[interface{}]interface{}
+
+func readConfigFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// decode into a plain map, yaml reuses the type of the target for nested mappings
+	var config map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return Config(config), nil
+}
+
+// apply sets the flags from the config file and adds the notifiers it declares. It returns
+// the notification routes of the config file.
+func (c Config) apply(fs *flag.FlagSet, notifManager *NotifManager) ([]*NotifRoute, error) {
+	var notifierConfigs, routeConfigs []interface{}
+	err := applyConfigSection(fs, "", c, func(path string, value interface{}) (bool, error) {
+		var ok bool
+		switch path {
+		case "notifications.notifiers":
+			if notifierConfigs, ok = value.([]interface{}); !ok {
+				return true, fmt.Errorf("%s: expected a list", path)
+			}
+			return true, nil
+		case "notifications.routes":
+			if routeConfigs, ok = value.([]interface{}); !ok {
+				return true, fmt.Errorf("%s: expected a list", path)
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]Notifier)
+	for _, notifier := range notifManager.Notifiers {
+		names[notifier.NotifName()] = notifier
+	}
+	for i, notifierConfig := range notifierConfigs {
+		path := fmt.Sprintf("notifications.notifiers[%d]", i)
+		notifier, err := newConfigNotifier(path, notifierConfig)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := names[notifier.NotifName()]; exists {
+			return nil, fmt.Errorf("%s.name: notifier %q already exists", path, notifier.NotifName())
+		}
+		names[notifier.NotifName()] = notifier
+		notifManager.Notifiers = append(notifManager.Notifiers, notifier)
+	}
+
+	routes := make([]*NotifRoute, 0, len(routeConfigs))
+	for i, routeConfig := range routeConfigs {
+		route, err := newConfigRoute(fmt.Sprintf("notifications.routes[%d]", i), routeConfig, names)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// applyConfigSection sets the flag of every setting in a section. Settings that are not flags
+// are passed to special, which returns true when it handled the setting.
+func applyConfigSection(fs *flag.FlagSet, prefix string, section map[interface{}]interface{}, special func(string, interface{}) (bool, error)) error {
+	for _, entry := range sortedEntries(section) {
+		path := entry.key
+		if prefix != "" {
+			path = prefix + "." + entry.key
+		}
+		value := entry.value
+
+		if flagName, ok := configFlags[path]; ok {
+			if err := setConfigFlag(fs, path, flagName, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if handled, err := special(path, value); handled || err != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if containsString(path, configSections) {
+			subsection, ok := value.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("%s: expected a mapping", path)
+			}
+			if err := applyConfigSection(fs, path, subsection, special); err != nil {
+				return err
+			}
+			continue
+		}
+		return fmt.Errorf("%s: unknown setting", path)
+	}
+	return nil
+}
+
+func setConfigFlag(fs *flag.FlagSet, path, flagName string, value interface{}) error {
+	str, err := configValue(path, value, configMapSeparators[flagName])
+	if err != nil {
+		return err
+	}
+	if err := fs.Set(flagName, str); err != nil {
+		return fmt.Errorf("%s: invalid value %q: %v", path, str, err)
+	}
+	return nil
+}
+
+// configValue turns a config value into a flag value. Lists become comma separated values and
+// mappings become comma separated key<separator>value entries.
+func configValue(path string, value interface{}, separator string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ","), nil
+	case map[interface{}]interface{}:
+		if separator == "" {
+			return "", fmt.Errorf("%s: expected a value, not a mapping", path)
+		}
+		values := make(map[string]string)
+		for _, entry := range sortedEntries(v) {
+			values[entry.key] = fmt.Sprint(entry.value)
+		}
+		return joinMap(values, separator), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// newConfigNotifier creates a notifier declared in the config file. Its settings are the flags
+// of its type without the type prefix, e.g. url for -slack-url.
+func newConfigNotifier(path string, value interface{}) (Notifier, error) {
+	config, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a mapping", path)
+	}
+	name, _ := config["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%s.name: is required", path)
+	}
+	notifierType, _ := config["type"].(string)
+
+	var notifier Notifier
+	switch notifierType {
+	case "slack":
+		notifier = &SlackNotifier{Name: name, Detailed: true}
+	case "email":
+		notifier = &EmailNotifier{Name: name}
+	case "webhook":
+		notifier = &WebhookNotifier{Name: name}
+	case "pagerduty":
+		notifier = &PagerDutyNotifier{Name: name}
+	default:
+		return nil, fmt.Errorf("%s.type: expected slack, email, webhook or pagerduty", path)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	registerNotifierFlags(fs, notifier)
+	fs.Set("enable-"+notifierType, "true")
+	for _, entry := range sortedEntries(config) {
+		if entry.key == "name" || entry.key == "type" {
+			continue
+		}
+		flagName := notifierType + "-" + entry.key
+		if entry.key == "enabled" {
+			flagName = "enable-" + notifierType
+		}
+		if fs.Lookup(flagName) == nil {
+			return nil, fmt.Errorf("%s.%s: unknown %s notifier setting", path, entry.key, notifierType)
+		}
+		if err := setConfigFlag(fs, path+"."+entry.key, flagName, entry.value); err != nil {
+			return nil, err
+		}
+	}
+	return notifier, nil
+}
+
+func newConfigRoute(path string, value interface{}, notifiers map[string]Notifier) (*NotifRoute, error) {
+	config, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a mapping", path)
+	}
+	route := &NotifRoute{Labels: make(map[string]string)}
+	for _, entry := range sortedEntries(config) {
+		fieldPath := path + "." + entry.key
+		if entry.key == "labels" {
+			labels, ok := entry.value.(map[interface{}]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: expected a mapping", fieldPath)
+			}
+			for _, label := range sortedEntries(labels) {
+				route.Labels[label.key] = fmt.Sprint(label.value)
+			}
+			continue
+		}
+		if entry.key == "reminders" {
+			str, err := configValue(fieldPath, entry.value, "=")
+			if err != nil {
+				return nil, err
+			}
+			if route.Reminders, err = parseReminders(splitList(str)); err != nil {
+				return nil, fmt.Errorf("%s: %v", fieldPath, err)
+			}
+			continue
+		}
+
+		str, err := configValue(fieldPath, entry.value, "")
+		if err != nil {
+			return nil, err
+		}
+		values := splitList(str)
+		switch entry.key {
+		case "notifiers":
+			for _, name := range values {
+				notifier, ok := notifiers[name]
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown notifier %q", fieldPath, name)
+				}
+				route.Notifiers = append(route.Notifiers, notifier)
+			}
+		case "groups":
+			route.Groups = values
+		case "types":
+			route.Types = values
+		case "statuses":
+			route.Statuses = values
+		default:
+			return nil, fmt.Errorf("%s: unknown route setting", fieldPath)
+		}
+	}
+	if len(route.Notifiers) == 0 {
+		return nil, fmt.Errorf("%s.notifiers: is required", path)
+	}
+	return route, nil
+}
+
+// settingName names a setting by its config file path and its flag, for error messages.
+func settingName(flagName string) string {
+	for path, name := range configFlags {
+		if name == flagName {
+			return fmt.Sprintf("%s (-%s)", path, flagName)
+		}
+	}
+	return "-" + flagName
+}
+
+type configEntry struct {
+	key   string
+	value interface{}
+}
+
+// sortedEntries returns the entries of a config mapping sorted by key.
+func sortedEntries(m map[interface{}]interface{}) []configEntry {
+	entries := make([]configEntry, 0, len(m))
+	for key, value := range m {
+		entries = append(entries, configEntry{key: fmt.Sprint(key), value: value})
+	}
+	sort.Sort(byConfigKey(entries))
+	return entries
+}
+
+type byConfigKey []configEntry
+
+func (e byConfigKey) Len() int           { return len(e) }
+func (e byConfigKey) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byConfigKey) Less(i, j int) bool { return e[i].key < e[j].key }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValue(t *testing.T) {
+	tests := []struct {
+		value     interface{}
+		separator string
+		expected  string
+		err       bool
+	}{
+		{nil, "", "", false},
+		{"kube-system", "", "kube-system", false},
+		{30, "", "30", false},
+		{true, "", "true", false},
+		{[]interface{}{"slack", "email"}, "", "slack,email", false},
+		{[]interface{}{}, "", "", false},
+		{map[interface{}]interface{}{"X-Team": "core", "Authorization": "token"}, ":", "Authorization:token,X-Team:core", false},
+		{map[interface{}]interface{}{"Ready": "True:fail"}, "=", "Ready=True:fail", false},
+		{map[interface{}]interface{}{"Ready": "True:fail"}, "", "", true},
+	}
+	for _, test := range tests {
+		value, err := configValue("checks.node.value", test.value, test.separator)
+		if test.err {
+			assert.Error(t, err, test.value)
+			continue
+		}
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, value, test.value)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		flagName string
+		expected string
+	}{
+		{"config", "KUBE_ALERTS_CONFIG"},
+		{"node-check-interval", "KUBE_ALERTS_NODE_CHECK_INTERVAL"},
+		{"kv-configmap", "KUBE_ALERTS_KV_CONFIGMAP"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, envName(test.flagName))
+	}
+}
+
+func TestConfigFileArg(t *testing.T) {
+	env := envName("config")
+	defer os.Setenv(env, os.Getenv(env))
+
+	tests := []struct {
+		env      string
+		args     []string
+		expected string
+	}{
+		{"", []string{"-node-cpu-warn", "70"}, ""},
+		{"", []string{"-config", "a.yml"}, "a.yml"},
+		{"", []string{"--config", "a.yml"}, "a.yml"},
+		{"", []string{"-config=a.yml", "-node-cpu-warn", "70"}, "a.yml"},
+		{"", []string{"-config"}, ""},
+		{"", []string{"--", "-config", "a.yml"}, ""},
+		{"env.yml", []string{}, "env.yml"},
+		{"env.yml", []string{"--config=a.yml"}, "a.yml"},
+	}
+	for _, test := range tests {
+		os.Setenv(env, test.env)
+		assert.Equal(t, test.expected, configFileArg(test.args), test.args)
+	}
+}
+
+func TestSecondsValue(t *testing.T) {
+	var duration time.Duration
+	value := newSecondsValue(&duration, 30)
+	assert.Equal(t, 30*time.Second, duration)
+	assert.Equal(t, "30", value.String())
+
+	assert.NoError(t, value.Set("90"))
+	assert.Equal(t, 90*time.Second, duration)
+	assert.Equal(t, "90", value.String())
+
+	assert.Error(t, value.Set("1m"))
+	assert.Equal(t, 90*time.Second, duration)
+}
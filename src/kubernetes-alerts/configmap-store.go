@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// ConfigMapMaxConflicts is the number of times an update is applied again when the config map
+// was modified by someone else.
+const ConfigMapMaxConflicts = 3
+
+// configMapParts split the state across several config maps, so each stays well below the 1MB
+// limit of a config map. A part is named after the config map with its suffix and holds the keys
+// below its prefixes. The other keys, e.g. the history, are only kept in memory.
+var configMapParts = []struct {
+	suffix   string
+	prefixes []string
+}{
+	{"", []string{"kube-alerts/"}},
+	{"-outbox", []string{OutboxPrefix + "/", DeadLetterPrefix + "/"}},
+	{"-silences", []string{SilencePrefix + "/"}},
+	{"-transitions", []string{TransitionPrefix + "/"}},
+	{"-members", []string{MembersKey}},
+	{"-reminders", []string{RemindersKey}},
+}
+
+// ConfigMapStore keeps the state in config maps of the monitored cluster. The state is read from
+// memory and every change is written to the config map of its part.
+type ConfigMapStore struct {
+	*MemoryStore
+	kubernetes *KubernetesApi
+	parts      []*configMapPart
+}
+
+// configMapPart is a config map holding the keys below its prefixes.
+type configMapPart struct {
+	namespace       string
+	name            string
+	prefixes        []string
+	resourceVersion string
+	// pending holds the changes not written to the config map yet, nil for a deleted key
+	pending    map[string][]byte
+	updateLock sync.Mutex
+}
+
+// newConfigMapStore loads the state from the config maps named after configMap, in the form of
+// namespace/name. The config maps are created if they do not exist.
+func newConfigMapStore(kubernetes *KubernetesApi, configMap string) (*ConfigMapStore, error) {
+	namespace, name, err := splitConfigMapName(configMap)
+	if err != nil {
+		return nil, err
+	}
+	c := &ConfigMapStore{
+		MemoryStore: newMemoryStore(),
+		kubernetes:  kubernetes,
+	}
+	for _, part := range configMapParts {
+		p := &configMapPart{
+			namespace: namespace,
+			name:      name + part.suffix,
+			prefixes:  part.prefixes,
+			pending:   make(map[string][]byte),
+		}
+		if err := c.prepare(p); err != nil {
+			return nil, err
+		}
+		c.parts = append(c.parts, p)
+	}
+	return c, nil
+}
+
+// prepare loads a part, creating its config map if it does not exist.
+func (c *ConfigMapStore) prepare(p *configMapPart) error {
+	err := c.load(p)
+	if apiErr, ok := err.(*ApiError); ok && apiErr.StatusCode == http.StatusNotFound {
+		logrus.Infof("Creating config map %s/%s for the state...", p.namespace, p.name)
+		created, err := c.kubernetes.CreateConfigMap(ConfigMap{Metadata: ResourceMetadata{Namespace: p.namespace, Name: p.name}})
+		if err != nil {
+			return err
+		}
+		p.resourceVersion = created.Metadata.ResourceVersion
+		return nil
+	}
+	return err
+}
+
+func splitConfigMapName(configMap string) (string, string, error) {
+	parts := strings.Split(configMap, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid config map %q, expected namespace/name", configMap)
+	}
+	return parts[0], parts[1], nil
+}
+
+// part returns the part holding the key, nil if the key is only kept in memory.
+func (c *ConfigMapStore) part(key string) *configMapPart {
+	for _, p := range c.parts {
+		if hasAnyPrefix(key, p.prefixes) {
+			return p
+		}
+	}
+	return nil
+}
+
+// load replaces the keys of a part in memory with the ones of its config map, and applies the
+// pending changes again.
+func (c *ConfigMapStore) load(p *configMapPart) error {
+	configMap, err := c.kubernetes.ConfigMap(p.namespace, p.name)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(configMap.Data))
+	for key, value := range configMap.Data {
+		if key = unescapeConfigMapKey(key); hasAnyPrefix(key, p.prefixes) {
+			values[key] = value
+		}
+	}
+	for key, value := range p.pending {
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = string(value)
+		}
+	}
+	c.MemoryStore.reset(p.prefixes, values)
+	p.resourceVersion = configMap.Metadata.ResourceVersion
+	return nil
+}
+
+func (c *ConfigMapStore) save(p *configMapPart) error {
+	values := c.MemoryStore.values(p.prefixes)
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		data[escapeConfigMapKey(key)] = value
+	}
+	configMap := ConfigMap{
+		Metadata: ResourceMetadata{Namespace: p.namespace, Name: p.name, ResourceVersion: p.resourceVersion},
+		Data:     data,
+	}
+	updated, err := c.kubernetes.UpdateConfigMap(configMap)
+	if err != nil {
+		return err
+	}
+	p.resourceVersion = updated.Metadata.ResourceVersion
+	p.pending = make(map[string][]byte)
+	return nil
+}
+
+// update applies a change to a key and writes its part to the config map. Keys without a part
+// are only changed in memory. When the config map was modified by someone else, it is loaded
+// again and the change applied again. A change that cannot be written is kept pending and
+// written along with the next change.
+func (c *ConfigMapStore) update(key string, apply func() error) error {
+	key = normalizeKey(key)
+	p := c.part(key)
+	if p == nil {
+		return apply()
+	}
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	for attempt := 1; ; attempt++ {
+		previous, wasPending := p.pending[key]
+		if err := apply(); err != nil {
+			return err
+		}
+		p.pending[key] = nil
+		if pair, err := c.MemoryStore.Get(key); err == nil {
+			p.pending[key] = pair.Value
+		}
+
+		err := c.save(p)
+		if apiErr, ok := err.(*ApiError); ok && apiErr.StatusCode == http.StatusConflict && attempt < ConfigMapMaxConflicts {
+			logrus.Warnf("config map %s/%s was modified, applying the change again", p.namespace, p.name)
+			// the change is applied again on the loaded keys, e.g. an atomic put may fail now
+			change := p.pending[key]
+			if wasPending {
+				p.pending[key] = previous
+			} else {
+				delete(p.pending, key)
+			}
+			if err := c.load(p); err != nil {
+				p.pending[key] = change
+				return err
+			}
+			continue
+		}
+		return err
+	}
+}
+
+func (c *ConfigMapStore) Put(key string, value []byte, options *store.WriteOptions) error {
+	return c.update(key, func() error {
+		return c.MemoryStore.Put(key, value, options)
+	})
+}
+
+func (c *ConfigMapStore) Delete(key string) error {
+	return c.update(key, func() error {
+		return c.MemoryStore.Delete(key)
+	})
+}
+
+func (c *ConfigMapStore) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	var pair *store.KVPair
+	err := c.update(key, func() error {
+		var err error
+		_, pair, err = c.MemoryStore.AtomicPut(key, value, previous, options)
+		return err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return true, pair, nil
+}
+
+// escapeConfigMapKey turns a key into a valid config map key, which only allows alphanumerics,
+// '-', '_' and '.'. Other characters and '_' itself are escaped as _<hex>, e.g. '/' as _2f.
+func escapeConfigMapKey(key string) string {
+	escaped := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '-', b == '.':
+			escaped = append(escaped, b)
+		default:
+			escaped = append(escaped, []byte(fmt.Sprintf("_%02x", b))...)
+		}
+	}
+	return string(escaped)
+}
+
+func unescapeConfigMapKey(key string) string {
+	unescaped := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		var b byte
+		if key[i] == '_' && i+2 < len(key) {
+			if _, err := fmt.Sscanf(key[i+1:i+3], "%02x", &b); err == nil {
+				unescaped = append(unescaped, b)
+				i += 2
+				continue
+			}
+		}
+		unescaped = append(unescaped, key[i])
+	}
+	return string(unescaped)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeConfigMapKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		escaped string
+	}{
+		{"kube-alerts", "kube-alerts"},
+		{"kube-alerts/node/node-ready/node-1", "kube-alerts_2fnode_2fnode-ready_2fnode-1"},
+		{"kube-alerts/pod/pod-crash-loop/default/web-1/nginx", "kube-alerts_2fpod_2fpod-crash-loop_2fdefault_2fweb-1_2fnginx"},
+		{"node_1.example.com", "node_5f1.example.com"},
+		{"ip:10.0.0.1", "ip_3a10.0.0.1"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.escaped, escapeConfigMapKey(test.key))
+		assert.Equal(t, test.key, unescapeConfigMapKey(test.escaped))
+	}
+}
+
+func TestUnescapeConfigMapKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		unescaped string
+	}{
+		{"a_2fb", "a/b"},
+		{"a_zzb", "a_zzb"},
+		{"a_2", "a_2"},
+		{"a_", "a_"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.unescaped, unescapeConfigMapKey(test.key))
+	}
+}
+
+func TestSplitConfigMapName(t *testing.T) {
+	tests := []struct {
+		configMap string
+		namespace string
+		name      string
+		err       bool
+	}{
+		{"kube-system/kube-alerts", "kube-system", "kube-alerts", false},
+		{"kube-alerts", "", "", true},
+		{"/kube-alerts", "", "", true},
+		{"kube-system/", "", "", true},
+		{"kube-system/kube-alerts/state", "", "", true},
+	}
+	for _, test := range tests {
+		namespace, name, err := splitConfigMapName(test.configMap)
+		if test.err {
+			assert.Error(t, err, test.configMap)
+			continue
+		}
+		assert.NoError(t, err, test.configMap)
+		assert.Equal(t, test.namespace, namespace)
+		assert.Equal(t, test.name, name)
+	}
+}
+
+// configMapServer serves the config maps of the kube-system namespace.
+type configMapServer struct {
+	sync.Mutex
+	configMaps      map[string]*ConfigMap
+	resourceVersion int
+	// conflicts is the number of updates failing because the config map was modified meanwhile
+	conflicts int
+}
+
+func (s *configMapServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	const path = "/namespaces/kube-system/configmaps"
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, path), "/")
+	var configMap ConfigMap
+	switch {
+	case r.Method == "GET" && name != "":
+		if s.configMaps[name] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		configMap = *s.configMaps[name]
+	case r.Method == "POST" && r.URL.Path == path:
+		json.NewDecoder(r.Body).Decode(&configMap)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && s.configMaps[name] != nil:
+		json.NewDecoder(r.Body).Decode(&configMap)
+		if s.conflicts > 0 {
+			s.conflicts--
+			s.resourceVersion++
+			s.configMaps[name].Metadata.ResourceVersion = strconv.Itoa(s.resourceVersion)
+		}
+		if configMap.Metadata.ResourceVersion != s.configMaps[name].Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, r.Method+" "+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	if r.Method != "GET" {
+		s.resourceVersion++
+		configMap.Metadata.ResourceVersion = strconv.Itoa(s.resourceVersion)
+		s.configMaps[configMap.Metadata.Name] = &configMap
+	}
+	json.NewEncoder(w).Encode(configMap)
+}
+
+func TestConfigMapStore(t *testing.T) {
+	server := &configMapServer{configMaps: make(map[string]*ConfigMap)}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	kubernetes := &KubernetesApi{ApiClient: &ApiClient{name: "kubernetes", apiBaseUrl: httpServer.URL}}
+	assert.NoError(t, kubernetes.prepareClient())
+
+	configMapStore, err := newConfigMapStore(kubernetes, "kube-system/kube-alerts")
+	assert.NoError(t, err)
+	assert.Len(t, server.configMaps, len(configMapParts))
+
+	kv := &KVClient{store: configMapStore}
+	check := KubeCheck{Name: "node-1", CheckGroup: CheckGroupNode, CheckType: "node-ready", Status: CheckStatusFail, Message: "node-1 is not ready"}
+	assert.NoError(t, kv.saveCheck(check))
+	assert.NoError(t, kv.putValue(SilencePrefix+"/1", Silence{ID: "1"}))
+	assert.NoError(t, kv.putValue(outboxKey("slack", "1"), PendingNotif{ID: "1", Notifier: "slack"}))
+	assert.NoError(t, kv.putValue(HistoryPrefix+"/node/node-ready/node-1/1", HistoryEntry{Name: "node-1"}))
+
+	// an update conflicting with another writer is applied again on the latest config map
+	server.conflicts = 1
+	other := check
+	other.Name = "node-2"
+	assert.NoError(t, kv.saveCheck(other))
+	assert.NoError(t, kv.deleteKey(checkKey(check.CheckGroup, check.CheckType, check.Name)))
+
+	assert.Len(t, server.configMaps["kube-alerts"].Data, 1)
+	assert.Contains(t, server.configMaps["kube-alerts"].Data, "kube-alerts_2fnode_2fnode-ready_2fnode-2")
+	assert.Len(t, server.configMaps["kube-alerts-silences"].Data, 1)
+	assert.Len(t, server.configMaps["kube-alerts-outbox"].Data, 1)
+
+	reloaded, err := newConfigMapStore(kubernetes, "kube-system/kube-alerts")
+	assert.NoError(t, err)
+	kv = &KVClient{store: reloaded}
+	checks, err := kv.listChecks()
+	assert.NoError(t, err)
+	assert.Len(t, checks, 1)
+	assert.Equal(t, "node-2", checks[0].Name)
+	assert.Equal(t, CheckStatusFail, checks[0].Status)
+
+	var silence Silence
+	assert.NoError(t, kv.getValue(SilencePrefix+"/1", &silence))
+	assert.Equal(t, "1", silence.ID)
+	var pending PendingNotif
+	assert.NoError(t, kv.getValue(outboxKey("slack", "1"), &pending))
+	assert.Equal(t, "slack", pending.Notifier)
+
+	// the history is only kept in memory
+	_, err = reloaded.Get(HistoryPrefix + "/node/node-ready/node-1/1")
+	assert.Equal(t, store.ErrKeyNotFound, err)
+}
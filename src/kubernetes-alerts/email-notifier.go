@@ -11,6 +11,7 @@ import (
 )
 
 type EmailNotifier struct {
+	Name        string
 	Enabled     bool
 	ClusterName string
 	Template    string
@@ -87,6 +88,10 @@ func (email *EmailNotifier) NotifEnabled() bool {
 	return email.Enabled
 }
 
+func (email *EmailNotifier) NotifName() string {
+	return email.Name
+}
+
 func mapByNodes(checks []KubeCheck) map[string][]KubeCheck {
 	nodeMap := make(map[string][]KubeCheck)
 	for _, check := range checks {
@@ -158,6 +163,12 @@ var defaultTemplate string = `
 					<strong>Since: </strong>
 					<span>{{ $check.Timestamp }}</span>
 				</div>
+				{{ with $check.Acknowledgement }}
+				<div style="font-size: 0.85em;">
+					<strong>Acknowledged by: </strong>
+					<span>{{ .By }}{{ with .Comment }} ({{ . }}){{ end }}</span>
+				</div>
+				{{ end }}
 				{{ with $check.Notes }}
 				<div style="padding-top: 15px;">
 					<strong>Notes: </strong>
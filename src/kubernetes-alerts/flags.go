@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// secondsValue is a flag holding a duration set in seconds.
+type secondsValue struct {
+	duration *time.Duration
+}
+
+func newSecondsValue(duration *time.Duration, seconds int) *secondsValue {
+	*duration = time.Duration(seconds) * time.Second
+	return &secondsValue{duration}
+}
+
+func (v *secondsValue) String() string {
+	if v.duration == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v.duration / time.Second))
+}
+
+func (v *secondsValue) Set(value string) error {
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*v.duration = time.Duration(seconds) * time.Second
+	return nil
+}
+
+// stringListValue is a flag holding a comma separated list.
+type stringListValue struct {
+	list *[]string
+}
+
+func (v *stringListValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v *stringListValue) Set(value string) error {
+	*v.list = splitList(value)
+	return nil
+}
+
+// repeatedValue collects the values of a flag that can be repeated.
+type repeatedValue struct {
+	list *[]string
+}
+
+func (v *repeatedValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, " ")
+}
+
+func (v *repeatedValue) Set(value string) error {
+	*v.list = append(*v.list, value)
+	return nil
+}
+
+// headersValue is a flag holding a comma separated list of Name:Value headers.
+type headersValue struct {
+	headers *map[string]string
+}
+
+func (v *headersValue) String() string {
+	if v.headers == nil {
+		return ""
+	}
+	return joinMap(*v.headers, ":")
+}
+
+func (v *headersValue) Set(value string) error {
+	headers, err := parseHeaders(value)
+	if err != nil {
+		return err
+	}
+	*v.headers = headers
+	return nil
+}
+
+// conditionRulesValue is a flag holding node condition rules.
+type conditionRulesValue struct {
+	rules *map[string]NodeConditionRule
+}
+
+func newConditionRulesValue(rules *map[string]NodeConditionRule, value string) *conditionRulesValue {
+	v := &conditionRulesValue{rules}
+	if err := v.Set(value); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v *conditionRulesValue) String() string {
+	if v.rules == nil {
+		return ""
+	}
+	rules := make(map[string]string)
+	for conditionType, rule := range *v.rules {
+		rules[conditionType] = rule.String()
+	}
+	return joinMap(rules, "=")
+}
+
+func (v *conditionRulesValue) Set(value string) error {
+	rules, err := parseNodeConditionRules(value)
+	if err != nil {
+		return err
+	}
+	*v.rules = rules
+	return nil
+}
+
+// conditionRuleValue is a flag holding a single node condition rule.
+type conditionRuleValue struct {
+	rule *NodeConditionRule
+}
+
+func newConditionRuleValue(rule *NodeConditionRule, value string) *conditionRuleValue {
+	v := &conditionRuleValue{rule}
+	if err := v.Set(value); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v *conditionRuleValue) String() string {
+	if v.rule == nil {
+		return ""
+	}
+	return v.rule.String()
+}
+
+func (v *conditionRuleValue) Set(value string) error {
+	rule, err := parseNodeConditionRule(value)
+	if err != nil {
+		return err
+	}
+	*v.rule = rule
+	return nil
+}
+
+// backendValue is a flag holding a libkv backend.
+type backendValue struct {
+	backend *store.Backend
+}
+
+func (v *backendValue) String() string {
+	if v.backend == nil {
+		return ""
+	}
+	return string(*v.backend)
+}
+
+func (v *backendValue) Set(value string) error {
+	switch value {
+	case "etcd":
+		*v.backend = store.ETCD
+	case "consul":
+		*v.backend = store.CONSUL
+	case "zk":
+		*v.backend = store.ZK
+	case "boltdb":
+		*v.backend = store.BOLTDB
+	case "memory":
+		*v.backend = MemoryBackend
+	case "configmap":
+		*v.backend = ConfigMapBackend
+	default:
+		return fmt.Errorf("unknown backend %q", value)
+	}
+	return nil
+}
+
+// logLevelValue is a flag holding a logrus level.
+type logLevelValue struct {
+	level *logrus.Level
+}
+
+func newLogLevelValue(level *logrus.Level, value logrus.Level) *logLevelValue {
+	*level = value
+	return &logLevelValue{level}
+}
+
+func (v *logLevelValue) String() string {
+	if v.level == nil {
+		return ""
+	}
+	return v.level.String()
+}
+
+func (v *logLevelValue) Set(value string) error {
+	level, err := logrus.ParseLevel(value)
+	if err != nil {
+		return err
+	}
+	*v.level = level
+	return nil
+}
+
+// joinMap joins a map into a sorted, comma separated list of key<separator>value entries.
+func joinMap(values map[string]string, separator string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, key+separator+values[key])
+	}
+	return strings.Join(entries, ",")
+}
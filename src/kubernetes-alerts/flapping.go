@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+const TransitionPrefix = "kube-alerts-transitions"
+
+// transitionKey returns the key holding the status transitions of a check.
+func transitionKey(check KubeCheck) string {
+	return strings.Replace(checkKey(check.CheckGroup, check.CheckType, check.Name), "kube-alerts/", TransitionPrefix+"/", 1)
+}
+
+// recordTransition adds a status transition to the history of the check and returns the number
+// of transitions within the flap window.
+func (c *CheckProcessor) recordTransition(check KubeCheck, now time.Time) (int, error) {
+	key := transitionKey(check)
+	var transitions []time.Time
+	if err := c.getValue(key, &transitions); err != nil && err != store.ErrKeyNotFound {
+		return 0, err
+	}
+
+	recent := make([]time.Time, 0, len(transitions)+1)
+	for _, transition := range append(transitions, now) {
+		if now.Sub(transition) <= c.FlapWindow {
+			recent = append(recent, transition)
+		}
+	}
+	if err := c.putValue(key, recent); err != nil {
+		return 0, err
+	}
+	return len(recent), nil
+}
+
+// processFlapping ends the flapping of a check once it has kept its status for the flap window.
+func (c *CheckProcessor) processFlapping(check KubeCheck) {
+	if c.FlapTransitions > 0 && time.Since(check.Timestamp) < c.FlapWindow {
+		return
+	}
+	check.Flapping = false
+	if err := c.saveCheck(check); err != nil {
+		logrus.WithError(err).Warnf("Unable to save")
+		return
+	}
+	logrus.Infof("check %s is stable again, will notify", check.Name)
+	check.Message = fmt.Sprintf("%s (stable again after flapping)", check.Message)
+	c.addNotification(check)
+}
+
+func flappingMessage(check KubeCheck, transitions int, window time.Duration) string {
+	return fmt.Sprintf("%s (flapping, %d status changes in %s)", check.Message, transitions, formatDuration(window))
+}
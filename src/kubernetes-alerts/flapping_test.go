@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordTransition(t *testing.T) {
+	tests := []struct {
+		previous []time.Duration
+		window   time.Duration
+		expected int
+	}{
+		{nil, time.Hour, 1},
+		{[]time.Duration{10 * time.Minute, 20 * time.Minute}, time.Hour, 3},
+		{[]time.Duration{30 * time.Minute, 2 * time.Hour}, time.Hour, 2},
+		{[]time.Duration{time.Hour}, time.Hour, 2},
+		{[]time.Duration{2 * time.Hour, 3 * time.Hour}, time.Hour, 1},
+	}
+	now := time.Now()
+	check := KubeCheck{Name: "node-1", CheckGroup: CheckGroupNode, CheckType: "node-ready"}
+	for _, test := range tests {
+		c := &CheckProcessor{KVClient: &KVClient{store: newMemoryStore()}, FlapWindow: test.window}
+		previous := make([]time.Time, 0, len(test.previous))
+		for _, ago := range test.previous {
+			previous = append(previous, now.Add(-ago))
+		}
+		assert.NoError(t, c.putValue(transitionKey(check), previous))
+
+		transitions, err := c.recordTransition(check, now)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, transitions, test.previous)
+
+		// the transitions outside of the window are dropped
+		var recorded []time.Time
+		assert.NoError(t, c.getValue(transitionKey(check), &recorded))
+		assert.Len(t, recorded, test.expected)
+	}
+}
+
+func TestTransitionKey(t *testing.T) {
+	check := KubeCheck{Name: "node-1", CheckGroup: CheckGroupNode, CheckType: "node-ready"}
+	assert.Equal(t, TransitionPrefix+"/node/node-ready/node-1", transitionKey(check))
+}
+
+func TestProcessCheckFlapping(t *testing.T) {
+	n := &NotifManager{notifChannel: make(chan KubeCheck, 10)}
+	c := &CheckProcessor{
+		KVClient:        &KVClient{store: newMemoryStore()},
+		NotifManager:    n,
+		FlapWindow:      time.Hour,
+		FlapTransitions: 3,
+	}
+
+	tests := []struct {
+		status   CheckStatus
+		notified string
+		flapping bool
+	}{
+		{CheckStatusFail, "node-1 fail", false},
+		{CheckStatusPass, "node-1 pass", false},
+		{CheckStatusFail, "node-1 fail", false},
+		{CheckStatusPass, "node-1 pass (flapping, 3 status changes in 1h)", true},
+		{CheckStatusFail, "", true},
+		{CheckStatusPass, "", true},
+		{CheckStatusPass, "", true},
+	}
+	for i, test := range tests {
+		c.processCheck(KubeCheck{
+			Name:       "node-1",
+			CheckGroup: CheckGroupNode,
+			CheckType:  "node-ready",
+			Status:     test.status,
+			Message:    "node-1 " + string(test.status),
+			Timestamp:  time.Now(),
+		})
+		if test.notified == "" {
+			assert.Len(t, n.notifChannel, 0, i)
+		} else if assert.Len(t, n.notifChannel, 1, i) {
+			assert.Equal(t, test.notified, (<-n.notifChannel).Message)
+		}
+		saved, err := c.getCheck(CheckGroupNode, "node-ready", "node-1")
+		assert.NoError(t, err)
+		assert.Equal(t, test.flapping, saved.Flapping, i)
+	}
+
+	// stable for the flap window
+	saved, err := c.getCheck(CheckGroupNode, "node-ready", "node-1")
+	assert.NoError(t, err)
+	saved.Timestamp = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, c.saveCheck(saved))
+	c.processCheck(KubeCheck{Name: "node-1", CheckGroup: CheckGroupNode, CheckType: "node-ready", Status: CheckStatusPass, Message: "node-1 pass", Timestamp: time.Now()})
+	if assert.Len(t, n.notifChannel, 1) {
+		assert.Equal(t, "node-1 pass (stable again after flapping)", (<-n.notifChannel).Message)
+	}
+	saved, err = c.getCheck(CheckGroupNode, "node-ready", "node-1")
+	assert.NoError(t, err)
+	assert.False(t, saved.Flapping)
+}
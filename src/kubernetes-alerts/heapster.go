@@ -1,5 +1,68 @@
 package main
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MetricCpuUsageRate     = "cpu/usage_rate"
+	MetricMemoryWorkingSet = "memory/working_set"
+)
+
 type HeapsterModelApi struct {
 	*ApiClient
 }
+
+type MetricResult struct {
+	Metrics         []MetricPoint `json:"metrics"`
+	LatestTimestamp time.Time     `json:"latestTimestamp"`
+}
+
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     uint64    `json:"value"`
+}
+
+func (h *HeapsterModelApi) Enabled() bool {
+	return h.ApiClient != nil && h.apiBaseUrl != ""
+}
+
+func (h *HeapsterModelApi) NodeMetric(node, metric string) (MetricResult, error) {
+	var result MetricResult
+	path := fmt.Sprintf("/nodes/%s/metrics/%s", node, metric)
+	err := h.GetRequest(path, &result)
+	return result, err
+}
+
+// Latest returns the value of the most recent metric point.
+func (m MetricResult) Latest() (uint64, error) {
+	if len(m.Metrics) == 0 {
+		return 0, fmt.Errorf("no metric points available")
+	}
+	latest := m.Metrics[0]
+	for _, point := range m.Metrics {
+		if point.Timestamp.After(latest.Timestamp) {
+			latest = point
+		}
+	}
+	return latest.Value, nil
+}
+
+// NodeCpuUsage returns the current cpu usage of the node in millicores.
+func (h *HeapsterModelApi) NodeCpuUsage(node string) (uint64, error) {
+	result, err := h.NodeMetric(node, MetricCpuUsageRate)
+	if err != nil {
+		return 0, err
+	}
+	return result.Latest()
+}
+
+// NodeMemoryWorkingSet returns the current memory working set of the node in bytes.
+func (h *HeapsterModelApi) NodeMemoryWorkingSet(node string) (uint64, error) {
+	result, err := h.NodeMetric(node, MetricMemoryWorkingSet)
+	if err != nil {
+		return 0, err
+	}
+	return result.Latest()
+}
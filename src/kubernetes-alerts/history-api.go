@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// listHistory serves the status changes of the checks within a time range, e.g.
+// GET /api/history?group=node&status=fail&since=24h
+func (h *HttpServer) listHistory(w http.ResponseWriter, r *http.Request) {
+	h.serveHistory(w, r, "")
+}
+
+// checkHistory serves the status changes of a single check by its key, e.g.
+// GET /api/history/node/node-ready/node-1?since=2016-06-01T00:00:00Z
+func (h *HttpServer) checkHistory(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/history/")
+	if _, ok := checkKeyFromPath(path); !ok {
+		writeError(w, http.StatusNotFound, "expected /api/history/<group>/<type>/<name>")
+		return
+	}
+	h.serveHistory(w, r, path)
+}
+
+func (h *HttpServer) serveHistory(w http.ResponseWriter, r *http.Request, key string) {
+	if !h.allowApi(w, r, "GET") {
+		return
+	}
+	if !h.History.enabled() {
+		writeError(w, http.StatusNotFound, "history is disabled")
+		return
+	}
+	filter := HistoryFilter{CheckFilter: newCheckFilter(r), Key: key}
+	now := time.Now()
+	var err error
+	if filter.Since, err = parseHistoryTime(r.URL.Query().Get("since"), now); err != nil {
+		writeError(w, http.StatusBadRequest, "since: "+err.Error())
+		return
+	}
+	if filter.Until, err = parseHistoryTime(r.URL.Query().Get("until"), now); err != nil {
+		writeError(w, http.StatusBadRequest, "until: "+err.Error())
+		return
+	}
+	entries, err := h.History.entries(filter)
+	if err != nil {
+		logrus.WithError(err).Error("unable to list history")
+		writeError(w, http.StatusInternalServerError, "unable to list history")
+		return
+	}
+	writeJson(w, http.StatusOK, entries)
+}
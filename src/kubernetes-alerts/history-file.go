@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileHistory appends the status changes to a local file, one JSON entry per line. The file is
+// only read by the replica writing it.
+type fileHistory struct {
+	path string
+	lock sync.Mutex
+}
+
+func newFileHistory(path string) (*fileHistory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &fileHistory{path: path}, nil
+}
+
+func (f *fileHistory) Append(entry HistoryEntry) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(entry); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *fileHistory) Entries(filter HistoryFilter) ([]HistoryEntry, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]HistoryEntry, 0)
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched, nil
+}
+
+// Expire rewrites the file without the entries before the given time.
+func (f *fileHistory) Expire(before time.Time) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return 0, err
+	}
+	kept := make([]HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Timestamp.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	removed := len(entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	tmp := f.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(file)
+	for _, entry := range kept {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return 0, err
+		}
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return removed, os.Rename(tmp, f.path)
+}
+
+func (f *fileHistory) read() ([]HistoryEntry, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries := make([]HistoryEntry, 0)
+	decoder := json.NewDecoder(file)
+	for {
+		var entry HistoryEntry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	HistoryPrefix = "kube-alerts-history"
+
+	HistoryBackendKV   = "kv"
+	HistoryBackendFile = "file"
+	HistoryBackendNone = "none"
+
+	// HistoryExpireInterval is the time between two removals of the expired history.
+	HistoryExpireInterval = time.Hour
+)
+
+// HistoryEntry is a status change of a check. The old status of a new check is empty.
+type HistoryEntry struct {
+	Key        string         `json:"key"`
+	Name       string         `json:"name"`
+	Node       string         `json:"node,omitempty"`
+	CheckGroup KubeCheckGroup `json:"checkGroup"`
+	CheckType  KubeCheckType  `json:"checkType"`
+	OldStatus  CheckStatus    `json:"oldStatus,omitempty"`
+	NewStatus  CheckStatus    `json:"newStatus"`
+	Message    string         `json:"message"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// HistoryStore keeps the status changes of the checks.
+type HistoryStore interface {
+	Append(entry HistoryEntry) error
+	Entries(filter HistoryFilter) ([]HistoryEntry, error)
+	Expire(before time.Time) (int, error)
+}
+
+// CheckHistory records the status changes of the checks in the KV store or in a local file,
+// and removes the changes older than the retention period every HistoryExpireInterval.
+type CheckHistory struct {
+	*KVClient
+	Backend      string
+	File         string
+	Retention    time.Duration
+	RunWaitGroup sync.WaitGroup
+	store        HistoryStore
+	stopChannel  chan bool
+	settingsLock sync.RWMutex
+}
+
+func (h *CheckHistory) prepare() error {
+	switch h.Backend {
+	case HistoryBackendKV:
+		h.store = &kvHistory{h.KVClient}
+	case HistoryBackendFile:
+		store, err := newFileHistory(h.File)
+		if err != nil {
+			return err
+		}
+		h.store = store
+	}
+	return nil
+}
+
+func (h *CheckHistory) enabled() bool {
+	return h != nil && h.store != nil
+}
+
+func (h *CheckHistory) retention() time.Duration {
+	h.settingsLock.RLock()
+	defer h.settingsLock.RUnlock()
+	return h.Retention
+}
+
+// start removes the expired history now and then every HistoryExpireInterval, until stopped.
+func (h *CheckHistory) start() {
+	h.RunWaitGroup.Add(1)
+	h.stopChannel = make(chan bool)
+	go h.run(h.stopChannel)
+}
+
+func (h *CheckHistory) stop() {
+	close(h.stopChannel)
+}
+
+func (h *CheckHistory) run(stop <-chan bool) {
+	defer h.RunWaitGroup.Done()
+	h.expire(time.Now())
+	ticker := time.NewTicker(HistoryExpireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			h.expire(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reload replaces the retention period with the updated one.
+func (h *CheckHistory) reload(updated *CheckHistory) {
+	h.settingsLock.Lock()
+	defer h.settingsLock.Unlock()
+	h.Retention = updated.Retention
+}
+
+// record adds a status change of a check to the history.
+func (h *CheckHistory) record(check KubeCheck, oldStatus CheckStatus) {
+	if !h.enabled() {
+		return
+	}
+	entry := HistoryEntry{
+		Key:        historyKey(check.CheckGroup, check.CheckType, check.Name),
+		Name:       check.Name,
+		Node:       check.Node,
+		CheckGroup: check.CheckGroup,
+		CheckType:  check.CheckType,
+		OldStatus:  oldStatus,
+		NewStatus:  check.Status,
+		Message:    check.Message,
+		Timestamp:  check.Timestamp,
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if err := h.store.Append(entry); err != nil {
+		logrus.WithError(err).Warnf("unable to record the history of check %s", check.Name)
+	}
+}
+
+func (h *CheckHistory) expire(now time.Time) {
+	retention := h.retention()
+	if retention <= 0 {
+		return
+	}
+	removed, err := h.store.Expire(now.Add(-retention))
+	if err != nil {
+		logrus.WithError(err).Warn("unable to remove the expired history")
+		return
+	}
+	if removed > 0 {
+		logrus.Infof("Removed %d history entries older than %s", removed, formatDuration(retention))
+	}
+}
+
+// entries returns the status changes matching the filter, oldest first.
+func (h *CheckHistory) entries(filter HistoryFilter) ([]HistoryEntry, error) {
+	entries, err := h.store.Entries(filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(byHistoryTime(entries))
+	return entries, nil
+}
+
+// historyKey identifies a check in the history, e.g. node/node-ready/node-1.
+func historyKey(checkGroup KubeCheckGroup, checkType KubeCheckType, checkName string) string {
+	return strings.TrimPrefix(checkKey(checkGroup, checkType, checkName), "kube-alerts/")
+}
+
+// HistoryFilter selects the status changes of a check, by its key, or of the checks matching
+// the check filter on their new status, within a time range. Zero times are not bounded.
+type HistoryFilter struct {
+	CheckFilter
+	Key   string
+	Since time.Time
+	Until time.Time
+}
+
+func (f HistoryFilter) matches(entry HistoryEntry) bool {
+	if f.Key != "" && entry.Key != f.Key {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	return f.CheckFilter.matches(KubeCheck{CheckGroup: entry.CheckGroup, CheckType: entry.CheckType, Status: entry.NewStatus, Node: entry.Node})
+}
+
+// parseHistoryTime parses a time in RFC3339 or a duration before now, e.g. 24h.
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or a duration", value)
+	}
+	return t, nil
+}
+
+type byHistoryTime []HistoryEntry
+
+func (e byHistoryTime) Len() int           { return len(e) }
+func (e byHistoryTime) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byHistoryTime) Less(i, j int) bool { return e[i].Timestamp.Before(e[j].Timestamp) }
+
+// kvHistory keeps every status change in its own key, below the key of its check, e.g.
+// kube-alerts-history/node/node-ready/node-1/<timestamp>.
+type kvHistory struct {
+	*KVClient
+}
+
+func (k *kvHistory) Append(entry HistoryEntry) error {
+	return k.putValue(fmt.Sprintf("%s/%s/%d", HistoryPrefix, entry.Key, entry.Timestamp.UnixNano()), entry)
+}
+
+func (k *kvHistory) Entries(filter HistoryFilter) ([]HistoryEntry, error) {
+	prefix := HistoryPrefix
+	if filter.Key != "" {
+		prefix += "/" + filter.Key
+	}
+	entries := make([]HistoryEntry, 0)
+	err := k.listEntries(prefix, func(key string, entry HistoryEntry) {
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+func (k *kvHistory) Expire(before time.Time) (int, error) {
+	removed := 0
+	err := k.listEntries(HistoryPrefix, func(key string, entry HistoryEntry) {
+		if !entry.Timestamp.Before(before) {
+			return
+		}
+		if err := k.deleteKey(key); err != nil {
+			logrus.WithError(err).Warnf("unable to remove history entry %s", key)
+			return
+		}
+		removed++
+	})
+	return removed, err
+}
+
+func (k *kvHistory) listEntries(prefix string, handle func(string, HistoryEntry)) error {
+	kvpairs, err := k.listValues(prefix)
+	if err != nil {
+		return err
+	}
+	for _, kvpair := range kvpairs {
+		var entry HistoryEntry
+		if err := json.Unmarshal(kvpair.Value, &entry); err != nil {
+			logrus.WithError(err).Warnf("unable to unmarshal history entry %s", kvpair.Key)
+			continue
+		}
+		handle(kvpair.Key, entry)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// HealthMinTimeout is the minimum time without a check cycle before a checker is considered hung.
+const HealthMinTimeout = time.Minute
+
+// HttpServer serves the health, readiness and metrics endpoints and the API of kube-alerts.
+type HttpServer struct {
+	Address        string
+	ApiToken       string
+	KVClient       *KVClient
+	LeaderElector  *LeaderElector
+	History        *CheckHistory
+	NotifManager   *NotifManager
+	NodeChecker    *NodeChecker
+	PodChecker     *PodChecker
+	ClusterChecker *ClusterChecker
+
+	listener     net.Listener
+	readyLock    sync.RWMutex
+	ready        bool
+	settingsLock sync.RWMutex
+}
+
+func (h *HttpServer) start() error {
+	if h.Address == "" {
+		return nil
+	}
+	logrus.Infof("Starting HTTP server on %s...", h.Address)
+	listener, err := net.Listen("tcp", h.Address)
+	if err != nil {
+		return err
+	}
+	h.listener = listener
+
+	mux := http.NewServeMux()
+	h.routes(mux)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			logrus.WithError(err).Error("HTTP server stopped.")
+		}
+	}()
+	return nil
+}
+
+func (h *HttpServer) stop() {
+	if h.listener != nil {
+		h.listener.Close()
+	}
+}
+
+func (h *HttpServer) routes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.healthz)
+	mux.HandleFunc("/readyz", h.readyz)
+	mux.HandleFunc("/metrics", h.metrics)
+	mux.HandleFunc("/api/checks", h.listChecks)
+	mux.HandleFunc("/api/checks/", h.getCheck)
+	mux.HandleFunc("/api/silences", h.silences)
+	mux.HandleFunc("/api/silences/", h.silence)
+	mux.HandleFunc("/api/acks/", h.acknowledge)
+	mux.HandleFunc("/api/history", h.listHistory)
+	mux.HandleFunc("/api/history/", h.checkHistory)
+	mux.HandleFunc("/slack/actions", h.slackActions)
+}
+
+func (h *HttpServer) apiToken() string {
+	h.settingsLock.RLock()
+	defer h.settingsLock.RUnlock()
+	return h.ApiToken
+}
+
+// reload replaces the API token with the updated one.
+func (h *HttpServer) reload(updated *HttpServer) {
+	h.settingsLock.Lock()
+	defer h.settingsLock.Unlock()
+	h.ApiToken = updated.ApiToken
+}
+
+// setReady marks kube-alerts as ready once its clients are prepared and its services started.
+func (h *HttpServer) setReady() {
+	h.readyLock.Lock()
+	defer h.readyLock.Unlock()
+	h.ready = true
+}
+
+func (h *HttpServer) isReady() bool {
+	h.readyLock.RLock()
+	defer h.readyLock.RUnlock()
+	return h.ready
+}
+
+// healthz fails when a running checker has not completed a check cycle for 3 check intervals.
+func (h *HttpServer) healthz(w http.ResponseWriter, r *http.Request) {
+	if stale := h.staleCheckers(); len(stale) > 0 {
+		http.Error(w, "checkers not running: "+strings.Join(stale, ", "), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *HttpServer) readyz(w http.ResponseWriter, r *http.Request) {
+	if !h.isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *HttpServer) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.write(w)
+	writeGauge(w, "kube_alerts_notification_queue_depth", "Checks waiting in the notification channel.", h.NotifManager.queueDepth())
+	if h.LeaderElector.Enabled {
+		leader := 0
+		if h.LeaderElector.isLeader() {
+			leader = 1
+		}
+		writeGauge(w, "kube_alerts_leader", "1 if this replica is the leader.", leader)
+	}
+}
+
+// staleCheckers returns the checkers that have not run lately. Checkers are not running on a
+// standby replica.
+func (h *HttpServer) staleCheckers() []string {
+	if !h.isReady() || !h.NotifManager.running() {
+		return nil
+	}
+	stale := make([]string, 0)
+	isStale := func(checker KubeCheckGroup, interval time.Duration) {
+		timeout := 3 * interval
+		if timeout < HealthMinTimeout {
+			timeout = HealthMinTimeout
+		}
+		if time.Since(metrics.lastCycle(string(checker))) > timeout {
+			stale = append(stale, string(checker))
+		}
+	}
+	isStale(CheckGroupNode, h.NodeChecker.checkInterval())
+	if h.PodChecker.enabled() {
+		isStale(CheckGroupPod, h.PodChecker.checkInterval())
+	}
+	if h.ClusterChecker.enabled() {
+		isStale(CheckGroupCluster, h.ClusterChecker.checkInterval())
+	}
+	return stale
+}
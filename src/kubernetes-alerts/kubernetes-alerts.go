@@ -2,13 +2,20 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/docker/libkv/store/consul"
 	"github.com/docker/libkv/store/etcd"
+	"github.com/docker/libkv/store/zookeeper"
 )
 
 const (
@@ -20,6 +27,15 @@ const (
 	CheckTypeNodeOutOfDisk = KubeCheckType("node-out-of-disk")
 	CheckTypeNodeCpu       = KubeCheckType("node-cpu")
 	CheckTypeNodeMem       = KubeCheckType("node-mem")
+	CheckTypePodCrashLoop  = KubeCheckType("pod-crash-loop")
+	CheckTypePodImagePull  = KubeCheckType("pod-image-pull")
+	CheckTypePodRestarts   = KubeCheckType("pod-restarts")
+	CheckTypeComponent     = KubeCheckType("component-status")
+	CheckTypeApiServer     = KubeCheckType("api-server")
+	CheckTypeNodesReady    = KubeCheckType("nodes-ready")
+	CheckTypeNodeCount     = KubeCheckType("node-count")
+
+	CheckTypeNodeMembership = KubeCheckType("node-membership")
 
 	CheckStatusPass = CheckStatus("pass")
 	CheckStatusWarn = CheckStatus("warn")
@@ -39,29 +55,44 @@ type KubeCheck struct {
 	Message    string            `json:"message"`
 	Timestamp  time.Time         `json:"timestamp"`
 	Labels     map[string]string `json:"labels"`
+
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+	Flapping        bool             `json:"flapping,omitempty"`
 }
 
 func main() {
 
-	kubernetes := &KubernetesApi{ApiClient: &ApiClient{}}
-	heapster := &HeapsterModelApi{ApiClient: &ApiClient{}}
-	kv := &KVClient{}
-	slack := &SlackNotifier{Detailed: true}
-	email := &EmailNotifier{}
-
-	notifManager := &NotifManager{
-		Notifiers: []Notifier{slack, email},
+	if len(os.Args) > 1 && commands[os.Args[1]] != nil {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
 	}
 
-	nodeChecker := &NodeChecker{
-		KubernetesApi:    kubernetes,
-		HeapsterModelApi: heapster,
-		KVClient:         kv,
-		NotifManager:     notifManager,
+	settings, err := loadSettings(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("invalid configuration")
+		os.Exit(2)
+	}
+	logrus.SetLevel(settings.LogLevel)
+
+	kubernetes := settings.Kubernetes
+	heapster := settings.Heapster
+	kv := settings.KV
+	httpServer := settings.HttpServer
+	leaderElector := settings.LeaderElector
+
+	// catch the signals before anything is started, they are handled once kube-alerts is running
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	reloader := &ConfigReloader{Settings: settings, Args: os.Args[1:]}
+	reloader.catchSignals()
+
+	if err := httpServer.start(); err != nil {
+		logrus.WithError(err).Error("unable to start http server")
+		os.Exit(-1)
 	}
 
-	// need better way for configuring this...
-	parseFlags(kubernetes, heapster, kv, notifManager, nodeChecker, slack, email)
 	initLibKV()
 
 	if err := kubernetes.prepareClient(); err != nil {
@@ -79,88 +110,520 @@ func main() {
 		os.Exit(-1)
 	}
 
+	if err := settings.History.prepare(); err != nil {
+		logrus.WithError(err).Error("unable to prepare the check history")
+		os.Exit(-1)
+	}
+
 	logrus.Info("Starting kube-alerts...")
 
-	notifManager.Start()
-	nodeChecker.start()
+	if leaderElector.Enabled {
+		leaderElector.start(settings.startServices, settings.stopServices)
+	} else {
+		settings.startServices()
+	}
+
+	reloader.start()
+	httpServer.setReady()
 
-	nodeChecker.RunWaitGroup.Wait()
+	sig := <-signals
+	logrus.Infof("Received %s, stopping kube-alerts...", sig)
+
+	// clean up aka stop all services. the api and reloader are stopped first so checkers are
+	// not started again, and the checkers before the notif manager so no check is left behind.
+	httpServer.stop()
+	reloader.stop()
+	reloader.RunWaitGroup.Wait()
+
+	if leaderElector.Enabled {
+		leaderElector.stop()
+		leaderElector.RunWaitGroup.Wait()
+	}
+	settings.stopServices()
 
-	// clean up aka stop all services
+	kubernetes.close()
+	heapster.close()
+	kv.close()
+	logrus.Info("kube-alerts stopped.")
 }
 
-func parseFlags(kubernetes *KubernetesApi, heapster *HeapsterModelApi, kv *KVClient, notifManager *NotifManager, nodeChecker *NodeChecker, slack *SlackNotifier, email *EmailNotifier) {
-	flag.StringVar(&kubernetes.apiBaseUrl, "k8s-api", "", "Kubernetes API Base URL")
-	flag.StringVar(&kubernetes.certificateAuthority, "k8s-certificate-authority", "", "Kubernetes Certificate Authority")
-	flag.StringVar(&kubernetes.clientCertificate, "k8s-client-certificate", "", "Kubernetes Client Certificate")
-	flag.StringVar(&kubernetes.clientKey, "k8s-client-key", "", "Kubernetes Client Key")
-	flag.StringVar(&kubernetes.token, "k8s-token", "", "Kubernetes Token")
-	flag.StringVar(&kubernetes.tokenFile, "k8s-token-file", "", "Kubernetes Token File")
+// Settings holds the components of kube-alerts, configured from the config file, environment
+// variables and flags.
+type Settings struct {
+	ConfigFile     string
+	Kubernetes     *KubernetesApi
+	Heapster       *HeapsterModelApi
+	KV             *KVClient
+	NotifManager   *NotifManager
+	CheckProcessor *CheckProcessor
+	NodeChecker    *NodeChecker
+	PodChecker     *PodChecker
+	ClusterChecker *ClusterChecker
+	HttpServer     *HttpServer
+	LeaderElector  *LeaderElector
+	History        *CheckHistory
+	LogLevel       logrus.Level
+
+	ConfigWatchInterval time.Duration
+	ShutdownTimeout     time.Duration
+
+	servicesLock    sync.Mutex
+	servicesRunning bool
+
+	routeSpecs []string
+}
+
+func newSettings() *Settings {
+	kubernetes := &KubernetesApi{ApiClient: &ApiClient{name: "kubernetes"}}
+	heapster := &HeapsterModelApi{ApiClient: &ApiClient{name: "heapster"}}
+	kv := &KVClient{kubernetes: kubernetes}
+	slack := &SlackNotifier{Name: "slack", Detailed: true}
+	email := &EmailNotifier{Name: "email"}
+	webhook := &WebhookNotifier{Name: "webhook"}
+	pagerduty := &PagerDutyNotifier{Name: "pagerduty"}
+
+	notifManager := &NotifManager{
+		KVClient:  kv,
+		Notifiers: []Notifier{slack, email, webhook, pagerduty},
+	}
+
+	history := &CheckHistory{KVClient: kv}
 
-	flag.StringVar(&heapster.apiBaseUrl, "heapster-api", "", "Heapster API Base URL")
-	flag.StringVar(&heapster.certificateAuthority, "heapster-certificate-authority", "", "Heapster Certificate Authority")
-	flag.StringVar(&heapster.clientCertificate, "heapster-client-certificate", "", "Heapster Client Certificate")
-	flag.StringVar(&heapster.clientKey, "heapster-client-key", "", "Heapster Client Key")
-	flag.StringVar(&heapster.token, "heapster-token", "", "Heapster Token")
+	checkProcessor := &CheckProcessor{
+		KVClient:     kv,
+		NotifManager: notifManager,
+		History:      history,
+	}
 
-	flag.StringVar(&kv.certificateAuthority, "kv-certificate-authority", "", "KV Certificate Authority")
-	flag.StringVar(&kv.clientCertificate, "kv-client-certificate", "", "KV Client Certificate")
-	flag.StringVar(&kv.clientKey, "kv-client-key", "", "KV Client Key")
+	clusterChecker := &ClusterChecker{
+		KubernetesApi:  kubernetes,
+		CheckProcessor: checkProcessor,
+	}
 
-	notifIntervalSecs := flag.Int("notification-interval", 60, "the interval to wait before sending notifications (seconds)")
+	nodeChecker := &NodeChecker{
+		KubernetesApi:    kubernetes,
+		HeapsterModelApi: heapster,
+		CheckProcessor:   checkProcessor,
+		ClusterChecker:   clusterChecker,
+	}
 
-	nodeCheckIntervalSecs := flag.Int("node-check-interval", 10, "interval in seconds before running node checks")
-	nodeCheckThresholdSecs := flag.Int("node-check-threshold", 60, "threshold before marking a node status as changed")
+	podChecker := &PodChecker{
+		KubernetesApi:  kubernetes,
+		CheckProcessor: checkProcessor,
+	}
 
-	flag.BoolVar(&slack.Enabled, "enable-slack", false, "Enable slack notifier")
-	flag.StringVar(&slack.ClusterName, "slack-cluster-name", "", "Cluster name to display on slack notifications")
-	flag.StringVar(&slack.Url, "slack-url", "", "The slack URL for notification")
-	flag.StringVar(&slack.Username, "slack-username", "kube-alerts", "The slack username")
+	leaderElector := &LeaderElector{KVClient: kv}
 
-	flag.BoolVar(&email.Enabled, "enable-email", false, "Enable email notifier")
-	flag.StringVar(&email.ClusterName, "email-cluster-name", "kubernetes", "The name of the kubernetes cluster")
-	flag.StringVar(&email.Template, "email-template", "", "The email template file")
-	flag.StringVar(&email.Url, "email-url", "", "The smtp server URL")
-	flag.IntVar(&email.Port, "email-port", 0, "The smtp port")
-	flag.StringVar(&email.Username, "email-username", "", "The smtp username")
-	flag.StringVar(&email.Password, "email-password", "", "The smtp password")
-	flag.StringVar(&email.SenderAlias, "email-sender-alias", "kube-alerts", "The email sender alias")
-	flag.StringVar(&email.SenderEmail, "email-sender-email", "", "The email of the sender")
+	httpServer := &HttpServer{
+		KVClient:       kv,
+		LeaderElector:  leaderElector,
+		History:        history,
+		NotifManager:   notifManager,
+		NodeChecker:    nodeChecker,
+		PodChecker:     podChecker,
+		ClusterChecker: clusterChecker,
+	}
+
+	return &Settings{
+		Kubernetes:     kubernetes,
+		Heapster:       heapster,
+		KV:             kv,
+		NotifManager:   notifManager,
+		CheckProcessor: checkProcessor,
+		NodeChecker:    nodeChecker,
+		PodChecker:     podChecker,
+		ClusterChecker: clusterChecker,
+		HttpServer:     httpServer,
+		LeaderElector:  leaderElector,
+		History:        history,
+	}
+}
+
+// loadSettings configures kube-alerts from the config file, then environment variables and
+// then the command line flags, each overriding the previous.
+func loadSettings(args []string) (*Settings, error) {
+	s := newSettings()
+	fs := s.flagSet()
+
+	s.ConfigFile = configFileArg(args)
+	var routes []*NotifRoute
+	if s.ConfigFile != "" {
+		config, err := readConfigFile(s.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		if routes, err = config.apply(fs, s.NotifManager); err != nil {
+			return nil, fmt.Errorf("%s: %v", s.ConfigFile, err)
+		}
+	}
 
-	emailReceivers := flag.String("email-receivers", "", "Comma separated list of receiver's email")
-	email.Receivers = strings.Split(*emailReceivers, ",")
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envName(f.Name)
+		if value := os.Getenv(name); value != "" && envErr == nil {
+			if err := fs.Set(f.Name, value); err != nil {
+				envErr = fmt.Errorf("invalid value %q for environment variable %s: %v", value, name, err)
+			}
+		}
+	})
+	if envErr != nil {
+		return nil, envErr
+	}
 
-	addresses := flag.String("kv-addresses", "", "addresses for the KV store")
-	backend := flag.String("kv-backend", "", "KV Store Backend. Only etcd for now")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
 
-	logLevel := flag.String("log-level", "info", "set the log level, valid values are [debug, info, warn, error, fatal, panic]")
-	flag.Parse()
+	for _, spec := range s.routeSpecs {
+		route, err := parseNotifRoute(spec, s.notifiers())
+		if err != nil {
+			return nil, fmt.Errorf("invalid -notif-route: %v", err)
+		}
+		routes = append(routes, route)
+	}
+	s.NotifManager.Routes = routes
 
-	kv.addresses = strings.Split(*addresses, ",")
-	switch *backend {
-	case "etcd":
-		kv.backend = store.ETCD
-	case "consul":
-		kv.backend = store.CONSUL
-	case "zk":
-		kv.backend = store.ZK
-	case "boltdb":
-		kv.backend = store.BOLTDB
+	if err := s.validate(); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
 
-	notifManager.NotifInterval = time.Duration(*notifIntervalSecs) * time.Second
-	nodeChecker.CheckInterval = time.Duration(*nodeCheckIntervalSecs) * time.Second
-	nodeChecker.Threshold = time.Duration(*nodeCheckThresholdSecs) * time.Second
+// startServices starts the notif manager, the checkers and the removal of the expired history.
+func (s *Settings) startServices() {
+	s.servicesLock.Lock()
+	defer s.servicesLock.Unlock()
+	if s.servicesRunning {
+		return
+	}
+	s.NotifManager.Start()
+	if s.History.enabled() {
+		s.History.start()
+	}
+	if s.ClusterChecker.enabled() {
+		s.ClusterChecker.start()
+	}
+	s.NodeChecker.start()
+	if s.PodChecker.enabled() {
+		s.PodChecker.start()
+	}
+	s.servicesRunning = true
+}
 
-	logrusLevel, err := logrus.ParseLevel(*logLevel)
-	if err != nil {
-		logrus.SetLevel(logrus.InfoLevel)
-	} else {
-		logrus.SetLevel(logrusLevel)
+// stopServices stops the checkers, waiting for their running checks, and then the notif manager
+// so no check is left behind.
+func (s *Settings) stopServices() {
+	s.servicesLock.Lock()
+	defer s.servicesLock.Unlock()
+	if !s.servicesRunning {
+		return
+	}
+	if s.ClusterChecker.enabled() {
+		s.ClusterChecker.stop()
+	}
+	s.NodeChecker.stop()
+	if s.PodChecker.enabled() {
+		s.PodChecker.stop()
 	}
+	if s.History.enabled() {
+		s.History.stop()
+	}
+	s.NodeChecker.RunWaitGroup.Wait()
+	s.PodChecker.RunWaitGroup.Wait()
+	s.ClusterChecker.RunWaitGroup.Wait()
+	s.History.RunWaitGroup.Wait()
+
+	s.NotifManager.Stop(s.ShutdownTimeout)
+	s.servicesRunning = false
+}
+
+// reload applies the updated settings to the running components. Changes to the kubernetes,
+// heapster and KV connections are only applied on restart.
+func (s *Settings) reload(updated *Settings) {
+	if !s.Kubernetes.sameConnection(updated.Kubernetes.ApiClient) {
+		logrus.Warn("Kubernetes connection settings have changed, restart kube-alerts to apply them.")
+	}
+	if !s.Heapster.sameConnection(updated.Heapster.ApiClient) {
+		logrus.Warn("Heapster connection settings have changed, restart kube-alerts to apply them.")
+	}
+	if !s.KV.sameConnection(updated.KV) {
+		logrus.Warn("KV connection settings have changed, restart kube-alerts to apply them.")
+	}
+	if s.HttpServer.Address != updated.HttpServer.Address {
+		logrus.Warn("HTTP address has changed, restart kube-alerts to apply it.")
+	}
+	if s.LeaderElector.Enabled != updated.LeaderElector.Enabled || s.LeaderElector.LeaseDuration != updated.LeaderElector.LeaseDuration {
+		logrus.Warn("Leader election settings have changed, restart kube-alerts to apply them.")
+	}
+	if s.History.Backend != updated.History.Backend || s.History.File != updated.History.File {
+		logrus.Warn("History settings have changed, restart kube-alerts to apply them.")
+	}
+
+	s.LogLevel = updated.LogLevel
+	logrus.SetLevel(s.LogLevel)
+
+	s.HttpServer.reload(updated.HttpServer)
+	s.NotifManager.reload(updated.NotifManager)
+	s.CheckProcessor.reload(updated.CheckProcessor)
+	s.History.reload(updated.History)
+	s.servicesLock.Lock()
+	defer s.servicesLock.Unlock()
+	s.ClusterChecker.reload(updated.ClusterChecker, s.servicesRunning)
+	s.NodeChecker.reload(updated.NodeChecker)
+	s.PodChecker.reload(updated.PodChecker, s.servicesRunning)
+}
+
+func (s *Settings) notifiers() map[string]Notifier {
+	notifiers := make(map[string]Notifier)
+	for _, notifier := range s.NotifManager.Notifiers {
+		notifiers[notifier.NotifName()] = notifier
+	}
+	return notifiers
+}
+
+func (s *Settings) validate() error {
+	positive := map[string]time.Duration{
+		"node-check-interval":   s.NodeChecker.CheckInterval,
+		"notification-interval": s.NotifManager.NotifInterval,
+		"shutdown-timeout":      s.ShutdownTimeout,
+	}
+	if s.PodChecker.Enabled {
+		positive["pod-check-interval"] = s.PodChecker.CheckInterval
+	}
+	if s.ClusterChecker.Enabled {
+		positive["cluster-check-interval"] = s.ClusterChecker.CheckInterval
+	}
+	if s.LeaderElector.Enabled {
+		positive["leader-lease"] = s.LeaderElector.LeaseDuration
+	}
+	if s.CheckProcessor.FlapTransitions > 0 {
+		positive["flap-window"] = s.CheckProcessor.FlapWindow
+	}
+	for name, duration := range positive {
+		if duration <= 0 {
+			return fmt.Errorf("%s: must be greater than 0", settingName(name))
+		}
+	}
+
+	percents := map[string]float64{
+		"node-cpu-warn":             s.NodeChecker.CpuWarnPercent,
+		"node-cpu-fail":             s.NodeChecker.CpuFailPercent,
+		"node-mem-warn":             s.NodeChecker.MemWarnPercent,
+		"node-mem-fail":             s.NodeChecker.MemFailPercent,
+		"cluster-min-ready-percent": s.ClusterChecker.MinReadyPercent,
+	}
+	for name, percent := range percents {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("%s: must be between 0 and 100", settingName(name))
+		}
+	}
+
+	if s.ClusterChecker.MinNodes > 0 && !s.ClusterChecker.Enabled {
+		return fmt.Errorf("%s: needs %s", settingName("cluster-min-nodes"), settingName("enable-cluster-checks"))
+	}
+	if s.KV.backend == "" {
+		return fmt.Errorf("%s: is required", settingName("kv-backend"))
+	}
+	if s.KV.backend == store.BOLTDB {
+		if len(s.KV.addresses) != 1 {
+			return fmt.Errorf("%s: expected the path of the boltdb file", settingName("kv-addresses"))
+		}
+		if s.KV.bucket == "" {
+			return fmt.Errorf("%s: is required with boltdb", settingName("kv-bucket"))
+		}
+	}
+	if s.KV.backend == ConfigMapBackend {
+		if _, _, err := splitConfigMapName(s.KV.configMap); err != nil {
+			return fmt.Errorf("%s: %v", settingName("kv-configmap"), err)
+		}
+	}
+	switch s.History.Backend {
+	case HistoryBackendKV:
+		if s.KV.backend == ConfigMapBackend {
+			return fmt.Errorf("%s: the kv history is not supported with the configmap backend, expected file or none", settingName("history"))
+		}
+	case HistoryBackendNone:
+	case HistoryBackendFile:
+		if s.History.File == "" {
+			return fmt.Errorf("%s: is required with the file history", settingName("history-file"))
+		}
+	default:
+		return fmt.Errorf("%s: expected kv, file or none", settingName("history"))
+	}
+	if s.LeaderElector.Enabled && !s.KV.supportsLocks() {
+		return fmt.Errorf("%s: is not supported with the %s backend", settingName("leader-election"), s.KV.backend)
+	}
+	return nil
+}
+
+// envName returns the environment variable overriding a flag, e.g. KUBE_ALERTS_SLACK_URL for -slack-url.
+func envName(flagName string) string {
+	return "KUBE_ALERTS_" + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// configFileArg finds the config file in the arguments or the environment before the flags are parsed.
+func configFileArg(args []string) string {
+	configFile := os.Getenv(envName("config"))
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		switch {
+		case name == "config" && i+1 < len(args):
+			configFile = args[i+1]
+		case strings.HasPrefix(name, "config="):
+			configFile = strings.TrimPrefix(name, "config=")
+		}
+	}
+	return configFile
+}
+
+func (s *Settings) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("kube-alerts", flag.ContinueOnError)
+	fs.String("config", "", "YAML configuration file, flags and environment variables override its values")
+	fs.Var(newSecondsValue(&s.ConfigWatchInterval, 10), "config-watch-interval", "interval in seconds to check the config file for changes, 0 to only reload on SIGHUP")
+
+	kubernetes := s.Kubernetes
+	fs.StringVar(&kubernetes.apiBaseUrl, "k8s-api", "", "Kubernetes API Base URL")
+	fs.StringVar(&kubernetes.certificateAuthority, "k8s-certificate-authority", "", "Kubernetes Certificate Authority")
+	fs.StringVar(&kubernetes.clientCertificate, "k8s-client-certificate", "", "Kubernetes Client Certificate")
+	fs.StringVar(&kubernetes.clientKey, "k8s-client-key", "", "Kubernetes Client Key")
+	fs.StringVar(&kubernetes.token, "k8s-token", "", "Kubernetes Token")
+	fs.StringVar(&kubernetes.tokenFile, "k8s-token-file", "", "Kubernetes Token File")
+
+	heapster := s.Heapster
+	fs.StringVar(&heapster.apiBaseUrl, "heapster-api", "", "Heapster API Base URL")
+	fs.StringVar(&heapster.certificateAuthority, "heapster-certificate-authority", "", "Heapster Certificate Authority")
+	fs.StringVar(&heapster.clientCertificate, "heapster-client-certificate", "", "Heapster Client Certificate")
+	fs.StringVar(&heapster.clientKey, "heapster-client-key", "", "Heapster Client Key")
+	fs.StringVar(&heapster.token, "heapster-token", "", "Heapster Token")
+
+	kv := s.KV
+	fs.StringVar(&kv.certificateAuthority, "kv-certificate-authority", "", "KV Certificate Authority")
+	fs.StringVar(&kv.clientCertificate, "kv-client-certificate", "", "KV Client Certificate")
+	fs.StringVar(&kv.clientKey, "kv-client-key", "", "KV Client Key")
+	fs.Var(&stringListValue{&kv.addresses}, "kv-addresses", "addresses for the KV store")
+	fs.Var(&backendValue{&kv.backend}, "kv-backend", "KV Store Backend: etcd, consul, zk, boltdb, memory or configmap")
+	fs.StringVar(&kv.username, "kv-username", "", "KV username, for etcd")
+	fs.StringVar(&kv.password, "kv-password", "", "KV password, for etcd")
+	fs.StringVar(&kv.bucket, "kv-bucket", "kube-alerts", "BoltDB bucket holding the state")
+	fs.StringVar(&kv.consulToken, "kv-consul-token", "", "Consul ACL token")
+	fs.StringVar(&kv.configMap, "kv-configmap", "kube-system/kube-alerts-state", "namespace/name of the config map holding the checks, the rest of the state is kept in config maps named after it")
+
+	notifManager := s.NotifManager
+	fs.Var(newSecondsValue(&notifManager.NotifInterval, 60), "notification-interval", "the interval to wait before sending notifications (seconds)")
+	fs.Var(newSecondsValue(&notifManager.RetryInterval, 60), "notification-retry-interval", "the interval to wait before retrying a failed notification, doubled on every attempt (seconds)")
+	fs.Var(newSecondsValue(&notifManager.MaxRetryInterval, 3600), "notification-max-retry-interval", "the maximum interval between notification retries (seconds)")
+	fs.IntVar(&notifManager.MaxAttempts, "notification-max-attempts", 10, "attempts before a failed notification is moved to the dead letter keys, 0 to retry forever")
+	fs.Var(newSecondsValue(&notifManager.RemindFailInterval, 0), "reminder-fail-interval", "interval in seconds to notify failing checks again until they pass or are acknowledged, 0 to disable")
+	fs.Var(newSecondsValue(&notifManager.RemindWarnInterval, 0), "reminder-warn-interval", "interval in seconds to notify warning checks again until they pass or are acknowledged, 0 to disable")
+	fs.Var(&repeatedValue{&s.routeSpecs}, "notif-route", "notification route, e.g. notifiers=email;groups=node;types=node-out-of-disk;statuses=fail;labels=namespace=payments;reminders=fail=3600 (repeatable)")
+
+	checkProcessor := s.CheckProcessor
+	fs.Var(newSecondsValue(&checkProcessor.FlapWindow, 1800), "flap-window", "window in seconds for counting the status changes of a check")
+	fs.IntVar(&checkProcessor.FlapTransitions, "flap-transitions", 5, "status changes within the flap window before a check is flapping, 0 to disable")
+	fs.BoolVar(&checkProcessor.NotifyRemoved, "notify-removed", true, "notify the failing checks of deleted nodes and pods as resolved before removing them")
+
+	history := s.History
+	fs.StringVar(&history.Backend, "history", HistoryBackendKV, "where to record the status changes of the checks: kv, file or none")
+	fs.StringVar(&history.File, "history-file", "/var/lib/kube-alerts/history.log", "file recording the status changes with -history=file")
+	fs.Var(newSecondsValue(&history.Retention, 604800), "history-retention", "time in seconds to keep the status changes, 0 to keep them forever")
+
+	nodeChecker := s.NodeChecker
+	fs.Var(newSecondsValue(&nodeChecker.CheckInterval, 10), "node-check-interval", "interval in seconds before running node checks")
+	fs.Var(newSecondsValue(&nodeChecker.Threshold, 60), "node-check-threshold", "threshold before marking a node status as changed")
+	fs.Var(newConditionRulesValue(&nodeChecker.ConditionRules, DefaultNodeConditionRules), "node-conditions", "comma separated node condition rules (Type=HealthyStatus:severity, severity is warn, fail or ignore)")
+	fs.Var(newConditionRuleValue(&nodeChecker.DefaultConditionRule, DefaultNodeConditionRule), "node-condition-default", "rule (HealthyStatus:severity) for node conditions not in -node-conditions")
+	fs.Float64Var(&nodeChecker.CpuWarnPercent, "node-cpu-warn", 80, "node cpu usage (percent of capacity) before warning, 0 to disable")
+	fs.Float64Var(&nodeChecker.CpuFailPercent, "node-cpu-fail", 90, "node cpu usage (percent of capacity) before failing, 0 to disable")
+	fs.Float64Var(&nodeChecker.MemWarnPercent, "node-mem-warn", 80, "node memory working set (percent of capacity) before warning, 0 to disable")
+	fs.Float64Var(&nodeChecker.MemFailPercent, "node-mem-fail", 90, "node memory working set (percent of capacity) before failing, 0 to disable")
+	fs.BoolVar(&nodeChecker.MembershipChecks, "enable-node-membership-checks", false, "Notify nodes joining or leaving the cluster")
+
+	podChecker := s.PodChecker
+	fs.BoolVar(&podChecker.Enabled, "enable-pod-checks", false, "Enable pod checks")
+	fs.Var(newSecondsValue(&podChecker.CheckInterval, 10), "pod-check-interval", "interval in seconds before running pod checks")
+	fs.Var(newSecondsValue(&podChecker.Threshold, 60), "pod-check-threshold", "threshold before marking a container as stuck in CrashLoopBackOff or ImagePullBackOff")
+	fs.IntVar(&podChecker.RestartLimit, "pod-restart-limit", 5, "number of container restarts within the restart window before failing, 0 to disable")
+	fs.Var(newSecondsValue(&podChecker.RestartWindow, 600), "pod-restart-window", "window in seconds for counting container restarts")
+
+	clusterChecker := s.ClusterChecker
+	fs.BoolVar(&clusterChecker.Enabled, "enable-cluster-checks", false, "Enable cluster checks")
+	fs.Var(newSecondsValue(&clusterChecker.CheckInterval, 30), "cluster-check-interval", "interval in seconds before running cluster checks")
+	fs.Var(newSecondsValue(&clusterChecker.Threshold, 60), "cluster-check-threshold", "threshold before marking a cluster status as changed")
+	fs.Float64Var(&clusterChecker.MinReadyPercent, "cluster-min-ready-percent", 80, "minimum percent of Ready nodes before failing, 0 to disable")
+	fs.IntVar(&clusterChecker.MinNodes, "cluster-min-nodes", 0, "minimum number of nodes before warning, 0 to disable")
+
+	for _, notifier := range notifManager.Notifiers {
+		registerNotifierFlags(fs, notifier)
+	}
+
+	fs.BoolVar(&s.LeaderElector.Enabled, "leader-election", false, "Enable leader election, only the leader runs the checks and sends notifications")
+	fs.StringVar(&s.LeaderElector.Identity, "leader-id", "", "identity of this replica in the leader election, defaults to the hostname")
+	fs.Var(newSecondsValue(&s.LeaderElector.LeaseDuration, 15), "leader-lease", "time in seconds before a standby replica takes over from a dead leader")
+	fs.StringVar(&s.HttpServer.Address, "http-address", ":9000", "address of the health, readiness and metrics endpoints and the API, empty to disable")
+	fs.StringVar(&s.HttpServer.ApiToken, "api-token", "", "bearer token required by the API, without it the API only accepts requests from localhost")
+	fs.Var(newSecondsValue(&s.ShutdownTimeout, 10), "shutdown-timeout", "time in seconds to send the pending notifications on shutdown")
+	fs.Var(newLogLevelValue(&s.LogLevel, logrus.InfoLevel), "log-level", "set the log level, valid values are [debug, info, warn, error, fatal, panic]")
+	return fs
+}
+
+// registerNotifierFlags registers the flags of a notifier, named enable-<type> and <type>-<option>.
+func registerNotifierFlags(fs *flag.FlagSet, notifier Notifier) {
+	switch n := notifier.(type) {
+	case *SlackNotifier:
+		registerSlackFlags(fs, n)
+	case *EmailNotifier:
+		registerEmailFlags(fs, n)
+	case *WebhookNotifier:
+		registerWebhookFlags(fs, n)
+	case *PagerDutyNotifier:
+		registerPagerDutyFlags(fs, n)
+	}
+}
+
+func registerSlackFlags(fs *flag.FlagSet, slack *SlackNotifier) {
+	fs.BoolVar(&slack.Enabled, "enable-slack", false, "Enable slack notifier")
+	fs.StringVar(&slack.ClusterName, "slack-cluster-name", "", "Cluster name to display on slack notifications")
+	fs.StringVar(&slack.Url, "slack-url", "", "The slack URL for notification")
+	fs.StringVar(&slack.Username, "slack-username", "kube-alerts", "The slack username")
+	fs.StringVar(&slack.Channel, "slack-channel", "", "The slack channel, defaults to the channel of the webhook")
+	fs.StringVar(&slack.VerificationToken, "slack-verification-token", "", "The verification token of the slack app, enables the acknowledge buttons")
+}
+
+func registerEmailFlags(fs *flag.FlagSet, email *EmailNotifier) {
+	fs.BoolVar(&email.Enabled, "enable-email", false, "Enable email notifier")
+	fs.StringVar(&email.ClusterName, "email-cluster-name", "kubernetes", "The name of the kubernetes cluster")
+	fs.StringVar(&email.Template, "email-template", "", "The email template file")
+	fs.StringVar(&email.Url, "email-url", "", "The smtp server URL")
+	fs.IntVar(&email.Port, "email-port", 0, "The smtp port")
+	fs.StringVar(&email.Username, "email-username", "", "The smtp username")
+	fs.StringVar(&email.Password, "email-password", "", "The smtp password")
+	fs.StringVar(&email.SenderAlias, "email-sender-alias", "kube-alerts", "The email sender alias")
+	fs.StringVar(&email.SenderEmail, "email-sender-email", "", "The email of the sender")
+	fs.Var(&stringListValue{&email.Receivers}, "email-receivers", "Comma separated list of receiver's email")
+}
+
+func registerWebhookFlags(fs *flag.FlagSet, webhook *WebhookNotifier) {
+	fs.BoolVar(&webhook.Enabled, "enable-webhook", false, "Enable webhook notifier")
+	fs.StringVar(&webhook.ClusterName, "webhook-cluster-name", "kubernetes", "The cluster name available to the webhook template")
+	fs.Var(&stringListValue{&webhook.Urls}, "webhook-urls", "Comma separated list of URLs to post notifications to")
+	fs.Var(&headersValue{&webhook.Headers}, "webhook-headers", "Comma separated list of Name:Value headers to add to webhook requests")
+	fs.StringVar(&webhook.Template, "webhook-template", "", "The webhook body template file, defaults to a JSON array of the checks")
+	fs.StringVar(&webhook.Secret, "webhook-secret", "", "The secret used to sign webhook payloads (HMAC-SHA256)")
+}
 
+func registerPagerDutyFlags(fs *flag.FlagSet, pagerduty *PagerDutyNotifier) {
+	fs.BoolVar(&pagerduty.Enabled, "enable-pagerduty", false, "Enable PagerDuty notifier")
+	fs.StringVar(&pagerduty.ClusterName, "pagerduty-cluster-name", "kubernetes", "The cluster name to display on PagerDuty incidents")
+	fs.StringVar(&pagerduty.RoutingKey, "pagerduty-routing-key", "", "The PagerDuty Events API v2 integration (routing) key")
+	fs.StringVar(&pagerduty.Url, "pagerduty-url", PagerDutyEventsUrl, "The PagerDuty Events API v2 URL")
 }
 
 func initLibKV() {
+	boltdb.Register()
+	consul.Register()
 	etcd.Register()
+	zookeeper.Register()
 }
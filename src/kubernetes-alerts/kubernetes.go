@@ -1,13 +1,58 @@
 package main
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+	WatchEventError    = "ERROR"
+)
+
+// ErrResourceExpired is returned by a watch when the requested resource version is too old
+// and the resources have to be listed again.
+var ErrResourceExpired = errors.New("resource version expired")
 
 type KubernetesApi struct {
 	*ApiClient
 }
 
 type NodeList struct {
-	Items []Node `json:"items"`
+	Metadata ListMetadata `json:"metadata"`
+	Items    []Node       `json:"items"`
+}
+
+type ListMetadata struct {
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// NodeEvent is a change to the nodes, either a single node from a watch event or all nodes
+// after a list.
+type NodeEvent struct {
+	Type  string
+	Node  Node
+	Nodes []Node
+}
+
+type ApiStatus struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
 }
 
 type Node struct {
@@ -16,8 +61,17 @@ type Node struct {
 }
 
 type ResourceMetadata struct {
-	Name   string            `json:"name"`
-	Labels map[string]string `json:"labels"`
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Labels          map[string]string `json:"labels"`
+}
+
+type ConfigMap struct {
+	Kind       string            `json:"kind"`
+	ApiVersion string            `json:"apiVersion"`
+	Metadata   ResourceMetadata  `json:"metadata"`
+	Data       map[string]string `json:"data"`
 }
 
 type NodeStatus struct {
@@ -40,11 +94,179 @@ type NodeCondition struct {
 	Message            string    `json:"message"`
 }
 
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+type Pod struct {
+	Metadata ResourceMetadata `json:"metadata"`
+	Spec     PodSpec          `json:"spec"`
+	Status   PodStatus        `json:"status"`
+}
+
+type PodSpec struct {
+	NodeName string `json:"nodeName"`
+}
+
+type PodStatus struct {
+	Phase             string            `json:"phase"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses"`
+}
+
+type ContainerStatus struct {
+	Name         string         `json:"name"`
+	RestartCount int            `json:"restartCount"`
+	State        ContainerState `json:"state"`
+}
+
+type ContainerState struct {
+	Waiting *ContainerStateWaiting `json:"waiting"`
+}
+
+type ContainerStateWaiting struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type ComponentStatusList struct {
+	Items []ComponentStatus `json:"items"`
+}
+
+type ComponentStatus struct {
+	Metadata   ResourceMetadata     `json:"metadata"`
+	Conditions []ComponentCondition `json:"conditions"`
+}
+
+type ComponentCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
 func (k *KubernetesApi) Nodes() ([]Node, error) {
+	nodeList, err := k.NodeList()
+	if err != nil {
+		return nil, err
+	}
+	return nodeList.Items, nil
+}
+
+func (k *KubernetesApi) NodeList() (NodeList, error) {
 	var nodeList NodeList
 	err := k.GetRequest("/nodes", &nodeList)
+	return nodeList, err
+}
+
+// WatchNodes streams node events starting at resourceVersion to handler until the watch ends
+// or stop is closed. It returns the last resource version seen, to resume the watch from.
+func (k *KubernetesApi) WatchNodes(resourceVersion string, stop <-chan bool, handler func(NodeEvent)) (string, error) {
+	path := "/nodes?watch=true&resourceVersion=" + url.QueryEscape(resourceVersion)
+	body, err := k.StreamRequest(path, stop)
+	if apiErr, ok := err.(*ApiError); ok && apiErr.StatusCode == http.StatusGone {
+		return resourceVersion, ErrResourceExpired
+	}
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var event WatchEvent
+		if err := decoder.Decode(&event); err != nil {
+			select {
+			case <-stop:
+				return resourceVersion, nil
+			default:
+			}
+			if err == io.EOF {
+				return resourceVersion, nil
+			}
+			return resourceVersion, err
+		}
+
+		if event.Type == WatchEventError {
+			var status ApiStatus
+			if err := json.Unmarshal(event.Object, &status); err != nil {
+				return resourceVersion, err
+			}
+			if status.Code == http.StatusGone {
+				return resourceVersion, ErrResourceExpired
+			}
+			return resourceVersion, fmt.Errorf("watch error %d: %s", status.Code, status.Message)
+		}
+
+		var node Node
+		if err := json.Unmarshal(event.Object, &node); err != nil {
+			return resourceVersion, err
+		}
+		resourceVersion = node.Metadata.ResourceVersion
+		handler(NodeEvent{Type: event.Type, Node: node})
+	}
+}
+
+func (k *KubernetesApi) Pods() ([]Pod, error) {
+	var podList PodList
+	err := k.GetRequest("/pods", &podList)
 	if err != nil {
 		return nil, err
 	}
-	return nodeList.Items, nil
+	return podList.Items, nil
+}
+
+func (k *KubernetesApi) ComponentStatuses() ([]ComponentStatus, error) {
+	var componentStatusList ComponentStatusList
+	err := k.GetRequest("/componentstatuses", &componentStatusList)
+	if err != nil {
+		return nil, err
+	}
+	return componentStatusList.Items, nil
+}
+
+func (k *KubernetesApi) ConfigMap(namespace, name string) (ConfigMap, error) {
+	var configMap ConfigMap
+	err := k.JsonRequest("GET", configMapPath(namespace, name), nil, &configMap)
+	return configMap, err
+}
+
+func (k *KubernetesApi) CreateConfigMap(configMap ConfigMap) (ConfigMap, error) {
+	var created ConfigMap
+	configMap.Kind, configMap.ApiVersion = "ConfigMap", "v1"
+	err := k.JsonRequest("POST", configMapPath(configMap.Metadata.Namespace, ""), configMap, &created)
+	return created, err
+}
+
+// UpdateConfigMap replaces a config map. The update fails with a conflict if the config map
+// was modified since its resource version.
+func (k *KubernetesApi) UpdateConfigMap(configMap ConfigMap) (ConfigMap, error) {
+	var updated ConfigMap
+	configMap.Kind, configMap.ApiVersion = "ConfigMap", "v1"
+	err := k.JsonRequest("PUT", configMapPath(configMap.Metadata.Namespace, configMap.Metadata.Name), configMap, &updated)
+	return updated, err
+}
+
+func configMapPath(namespace, name string) string {
+	path := "/namespaces/" + url.QueryEscape(namespace) + "/configmaps"
+	if name != "" {
+		path += "/" + url.QueryEscape(name)
+	}
+	return path
+}
+
+// Healthz queries the /healthz endpoint of the API server the base url points to.
+func (k *KubernetesApi) Healthz() error {
+	base, err := url.Parse(k.apiBaseUrl)
+	if err != nil {
+		return err
+	}
+	base.Path = "/healthz"
+	statusCode, body, err := k.GetRawRequest(base.String())
+	if err != nil {
+		return err
+	}
+	if result := strings.TrimSpace(string(body)); statusCode != 200 || result != "ok" {
+		return fmt.Errorf("healthz returned %d: %s", statusCode, result)
+	}
+	return nil
 }
@@ -3,34 +3,97 @@ package main
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/libkv"
 	"github.com/docker/libkv/store"
 )
 
+// ConsulTokenEnv is the environment variable the consul client reads its ACL token from.
+const ConsulTokenEnv = "CONSUL_HTTP_TOKEN"
+
 type KVClient struct {
 	backend              store.Backend
 	addresses            []string
 	certificateAuthority string
 	clientCertificate    string
 	clientKey            string
-	store                store.Store
+	username             string
+	password             string
+	bucket               string
+	consulToken          string
+	configMap            string
+	kubernetes           *KubernetesApi
+	store                StateStore
+}
+
+func (kvc *KVClient) close() {
+	if kvc.store != nil {
+		kvc.store.Close()
+	}
+}
+
+// sameConnection returns true if the other client connects to the same KV store with the same credentials.
+func (kvc *KVClient) sameConnection(other *KVClient) bool {
+	return kvc.backend == other.backend &&
+		strings.Join(kvc.addresses, ",") == strings.Join(other.addresses, ",") &&
+		kvc.certificateAuthority == other.certificateAuthority &&
+		kvc.clientCertificate == other.clientCertificate &&
+		kvc.clientKey == other.clientKey &&
+		kvc.username == other.username &&
+		kvc.password == other.password &&
+		kvc.bucket == other.bucket &&
+		kvc.consulToken == other.consulToken &&
+		kvc.configMap == other.configMap
+}
+
+// supportsLocks returns true if the backend is shared by replicas and supports locks.
+func (kvc *KVClient) supportsLocks() bool {
+	switch kvc.backend {
+	case store.BOLTDB, MemoryBackend, ConfigMapBackend:
+		return false
+	}
+	return true
 }
 
 func (kvc *KVClient) prepareClient() error {
+	switch kvc.backend {
+	case MemoryBackend:
+		logrus.Warn("Keeping the state in memory, it is lost when kube-alerts restarts.")
+		kvc.store = newMemoryStore()
+		return nil
+	case ConfigMapBackend:
+		configMapStore, err := newConfigMapStore(kvc.kubernetes, kvc.configMap)
+		if err != nil {
+			logrus.Error("unable to load the state from the config map. ", err)
+			return err
+		}
+		kvc.store = configMapStore
+		return nil
+	}
+
 	hasCA := kvc.certificateAuthority != ""
 	hasCert := kvc.clientCertificate != ""
 	hasKey := kvc.clientKey != ""
 
 	config := &store.Config{
 		ConnectionTimeout: 5 * time.Second,
+		Username:          kvc.username,
+		Password:          kvc.password,
+	}
+	if kvc.backend == store.BOLTDB {
+		// kube-alerts is the only user of its BoltDB file, keep it open instead of opening it on every call
+		config.Bucket = kvc.bucket
+		config.PersistConnection = true
 	}
 	if hasCA || hasCert || hasKey {
 
@@ -68,7 +131,7 @@ func (kvc *KVClient) prepareClient() error {
 		}
 
 	}
-	store, err := libkv.NewStore(kvc.backend, kvc.addresses, config)
+	store, err := kvc.newStore(config)
 	if err != nil {
 		fmt.Println(err)
 		logrus.Error("unable to create kvclient. ", err)
@@ -78,8 +141,34 @@ func (kvc *KVClient) prepareClient() error {
 	return nil
 }
 
+// newStore creates the libkv store. The libkv config has no consul token and the consul client
+// only reads it from CONSUL_HTTP_TOKEN when it is created, so the token is only set in the
+// environment while the store is created.
+func (kvc *KVClient) newStore(config *store.Config) (store.Store, error) {
+	if kvc.backend != store.CONSUL || kvc.consulToken == "" {
+		return libkv.NewStore(kvc.backend, kvc.addresses, config)
+	}
+	previous, wasSet := os.LookupEnv(ConsulTokenEnv)
+	if err := os.Setenv(ConsulTokenEnv, kvc.consulToken); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if wasSet {
+			os.Setenv(ConsulTokenEnv, previous)
+		} else {
+			os.Unsetenv(ConsulTokenEnv)
+		}
+	}()
+	return libkv.NewStore(kvc.backend, kvc.addresses, config)
+}
+
+// checkKey returns the key a check is stored under.
+func checkKey(checkGroup KubeCheckGroup, checkType KubeCheckType, checkName string) string {
+	return fmt.Sprintf("kube-alerts/%s/%s/%s", checkGroup, checkType, checkName)
+}
+
 func (kvc *KVClient) checkExists(check KubeCheck) (bool, error) {
-	key := fmt.Sprintf("kube-alerts/%s/%s/%s", check.CheckGroup, check.CheckType, check.Name)
+	key := checkKey(check.CheckGroup, check.CheckType, check.Name)
 	exists, err := kvc.store.Exists(key)
 	if err != nil {
 		logrus.WithError(err).Error("unable to check key existence")
@@ -94,13 +183,13 @@ func (kvc *KVClient) saveCheck(check KubeCheck) error {
 		logrus.WithError(err).Error("unable to marshall check")
 		return err
 	}
-	key := fmt.Sprintf("kube-alerts/%s/%s/%s", check.CheckGroup, check.CheckType, check.Name)
+	key := checkKey(check.CheckGroup, check.CheckType, check.Name)
 	return kvc.store.Put(key, value, nil)
 }
 
 func (kvc *KVClient) getCheck(checkGroup KubeCheckGroup, checkType KubeCheckType, checkName string) (KubeCheck, error) {
 	var check KubeCheck
-	key := fmt.Sprintf("kube-alerts/%s/%s/%s", checkGroup, checkType, checkName)
+	key := checkKey(checkGroup, checkType, checkName)
 	kvpair, err := kvc.store.Get(key)
 	if err != nil {
 		logrus.WithError(err).Error("unable to get kv pair")
@@ -113,3 +202,97 @@ func (kvc *KVClient) getCheck(checkGroup KubeCheckGroup, checkType KubeCheckType
 	}
 	return check, nil
 }
+
+// listChecks returns every check recorded in the KV store, sorted by key.
+func (kvc *KVClient) listChecks() ([]KubeCheck, error) {
+	return kvc.listChecksBelow("kube-alerts")
+}
+
+// listGroupChecks returns the checks of a group recorded in the KV store, sorted by key.
+func (kvc *KVClient) listGroupChecks(checkGroup KubeCheckGroup) ([]KubeCheck, error) {
+	return kvc.listChecksBelow(fmt.Sprintf("kube-alerts/%s", checkGroup))
+}
+
+func (kvc *KVClient) listChecksBelow(prefix string) ([]KubeCheck, error) {
+	kvpairs, err := kvc.listValues(prefix)
+	if err != nil {
+		return nil, err
+	}
+	checks := make([]KubeCheck, 0, len(kvpairs))
+	for _, kvpair := range kvpairs {
+		var check KubeCheck
+		if err := json.Unmarshal(kvpair.Value, &check); err != nil {
+			logrus.WithError(err).Warnf("unable to unmarshal check %s", kvpair.Key)
+			continue
+		}
+		checks = append(checks, check)
+	}
+	sort.Sort(byCheckKey(checks))
+	return checks, nil
+}
+
+// byCheckKey sorts checks by their KV key.
+type byCheckKey []KubeCheck
+
+func (c byCheckKey) Len() int      { return len(c) }
+func (c byCheckKey) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byCheckKey) Less(i, j int) bool {
+	return checkKey(c[i].CheckGroup, c[i].CheckType, c[i].Name) < checkKey(c[j].CheckGroup, c[j].CheckType, c[j].Name)
+}
+
+func (kvc *KVClient) putValue(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logrus.WithError(err).Error("unable to marshall value")
+		return err
+	}
+	return kvc.store.Put(key, data, nil)
+}
+
+func (kvc *KVClient) getValue(key string, value interface{}) error {
+	kvpair, err := kvc.store.Get(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(kvpair.Value, value)
+}
+
+func (kvc *KVClient) deleteKey(key string) error {
+	err := kvc.store.Delete(key)
+	if err == store.ErrKeyNotFound {
+		return nil
+	}
+	return err
+}
+
+// listValues returns every key/value pair below prefix. Backends that only list direct
+// children (e.g. etcd) are walked recursively; keys are returned without a leading slash.
+// The prefix is listed as a directory since consul and boltdb match keys by string prefix,
+// e.g. kube-alerts would also match kube-alerts-silences.
+func (kvc *KVClient) listValues(prefix string) ([]*store.KVPair, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	kvpairs, err := kvc.store.List(prefix + "/")
+	if err == store.ErrKeyNotFound {
+		return []*store.KVPair{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make([]*store.KVPair, 0, len(kvpairs))
+	for _, kvpair := range kvpairs {
+		key := strings.TrimPrefix(kvpair.Key, "/")
+		if key == prefix || key == prefix+"/" {
+			continue
+		}
+		if len(kvpair.Value) == 0 {
+			children, err := kvc.listValues(key)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, children...)
+			continue
+		}
+		values = append(values, &store.KVPair{Key: key, Value: kvpair.Value, LastIndex: kvpair.LastIndex})
+	}
+	return values, nil
+}
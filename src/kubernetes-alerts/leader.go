@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+const LeaderKey = "kube-alerts-leader"
+
+// LeaderElector runs the services of kube-alerts on a single replica. The replicas compete for a
+// lock in the KV store, held for the lease duration and renewed by the leader. A standby replica
+// takes over once the lease of a dead leader has expired.
+type LeaderElector struct {
+	*KVClient
+	Enabled       bool
+	Identity      string
+	LeaseDuration time.Duration
+	RunWaitGroup  sync.WaitGroup
+	stopChannel   chan struct{}
+
+	leaderLock sync.RWMutex
+	leader     bool
+}
+
+// start campaigns for the leadership, calling lead when it is acquired and follow when it is lost.
+func (e *LeaderElector) start(lead, follow func()) {
+	if e.Identity == "" {
+		e.Identity, _ = os.Hostname()
+	}
+	logrus.Infof("Starting Leader Election as %s...", e.Identity)
+	e.RunWaitGroup.Add(1)
+	e.stopChannel = make(chan struct{})
+	go e.run(lead, follow)
+}
+
+// stop gives up the leadership, if held, and stops campaigning.
+func (e *LeaderElector) stop() {
+	close(e.stopChannel)
+}
+
+func (e *LeaderElector) run(lead, follow func()) {
+	defer e.RunWaitGroup.Done()
+	for {
+		locker, err := e.store.NewLock(LeaderKey, &store.LockOptions{Value: []byte(e.Identity), TTL: e.LeaseDuration})
+		if err != nil {
+			logrus.WithError(err).Error("Unable to create the leader lock.")
+			if !e.waitForRetry() {
+				return
+			}
+			continue
+		}
+
+		lost, err := locker.Lock(e.stopChannel)
+		select {
+		case <-e.stopChannel:
+			if err == nil {
+				locker.Unlock()
+			}
+			return
+		default:
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Unable to acquire the leader lock.")
+			if !e.waitForRetry() {
+				return
+			}
+			continue
+		}
+
+		logrus.Infof("%s is the leader, starting services...", e.Identity)
+		e.setLeader(true)
+		lead()
+
+		select {
+		case <-lost:
+			logrus.Warnf("%s lost the leadership, stopping services...", e.Identity)
+			follow()
+			e.setLeader(false)
+		case <-e.stopChannel:
+			follow()
+			e.setLeader(false)
+			if err := locker.Unlock(); err != nil {
+				logrus.WithError(err).Warn("Unable to release the leader lock.")
+			}
+			return
+		}
+	}
+}
+
+// waitForRetry waits for the lease duration and returns false if the elector was stopped meanwhile.
+func (e *LeaderElector) waitForRetry() bool {
+	select {
+	case <-time.After(e.LeaseDuration):
+		return true
+	case <-e.stopChannel:
+		return false
+	}
+}
+
+func (e *LeaderElector) setLeader(leader bool) {
+	e.leaderLock.Lock()
+	defer e.leaderLock.Unlock()
+	e.leader = leader
+}
+
+func (e *LeaderElector) isLeader() bool {
+	e.leaderLock.RLock()
+	defer e.leaderLock.RUnlock()
+	return e.leader
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics holds the counters of kube-alerts, served in the Prometheus text format.
+var metrics = newMetrics()
+
+type Metrics struct {
+	sync.Mutex
+	checkCycles  map[string]int64
+	lastCycles   map[string]time.Time
+	checkResults map[string]int64
+	apiErrors    map[string]int64
+	notifSent    map[string]int64
+	notifFailed  map[string]int64
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		checkCycles:  make(map[string]int64),
+		lastCycles:   make(map[string]time.Time),
+		checkResults: make(map[string]int64),
+		apiErrors:    make(map[string]int64),
+		notifSent:    make(map[string]int64),
+		notifFailed:  make(map[string]int64),
+	}
+}
+
+// checkerStarted marks a checker as alive before its first check cycle.
+func (m *Metrics) checkerStarted(checker string) {
+	m.Lock()
+	defer m.Unlock()
+	m.lastCycles[checker] = time.Now()
+}
+
+func (m *Metrics) checkCycle(checker string) {
+	m.Lock()
+	defer m.Unlock()
+	m.checkCycles[checker]++
+	m.lastCycles[checker] = time.Now()
+}
+
+// lastCycle returns when the checker last ran its checks.
+func (m *Metrics) lastCycle(checker string) time.Time {
+	m.Lock()
+	defer m.Unlock()
+	return m.lastCycles[checker]
+}
+
+func (m *Metrics) checkResult(check KubeCheck) {
+	m.Lock()
+	defer m.Unlock()
+	m.checkResults[labels("group", string(check.CheckGroup), "type", string(check.CheckType), "status", string(check.Status))]++
+}
+
+func (m *Metrics) apiError(api string) {
+	m.Lock()
+	defer m.Unlock()
+	m.apiErrors[labels("api", api)]++
+}
+
+func (m *Metrics) notification(notifier string, sent bool) {
+	m.Lock()
+	defer m.Unlock()
+	if sent {
+		m.notifSent[labels("notifier", notifier)]++
+	} else {
+		m.notifFailed[labels("notifier", notifier)]++
+	}
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *Metrics) write(w io.Writer) {
+	m.Lock()
+	defer m.Unlock()
+	writeCounter(w, "kube_alerts_check_cycles_total", "Check cycles run by checker.", labelCounts(m.checkCycles, "checker"))
+	writeCounter(w, "kube_alerts_check_results_total", "Check results by group, type and status.", m.checkResults)
+	writeCounter(w, "kube_alerts_api_errors_total", "Failed Kubernetes and Heapster API requests.", m.apiErrors)
+	writeCounter(w, "kube_alerts_notifications_sent_total", "Notifications sent by notifier.", m.notifSent)
+	writeCounter(w, "kube_alerts_notifications_failed_total", "Notifications failed by notifier.", m.notifFailed)
+}
+
+func writeGauge(w io.Writer, name, help string, value int) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+func writeCounter(w io.Writer, name, help string, counts map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %d\n", name, key, counts[key])
+	}
+}
+
+// labelCounts turns counts keyed by a single label value into counts keyed by label set.
+func labelCounts(counts map[string]int64, name string) map[string]int64 {
+	labelled := make(map[string]int64, len(counts))
+	for value, count := range counts {
+		labelled[labels(name, value)] = count
+	}
+	return labelled
+}
+
+// labels formats name and value pairs as a Prometheus label set, e.g. {group="node"}.
+func labels(pairs ...string) string {
+	set := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		set = append(set, fmt.Sprintf(`%s="%s"`, pairs[i], value))
+	}
+	return "{" + strings.Join(set, ",") + "}"
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/Sirupsen/logrus"
 )
@@ -15,131 +18,287 @@ const (
 	NodeCheckOutOfDisk = "NodeOutOfDisk"
 	NodeCheckCpu       = "NodeCpu"
 	NodeCheckMem       = "NodeMem"
+
+	NodeEventSync = "SYNC"
+
+	ConditionSeverityIgnore = CheckStatus("ignore")
+
+	DefaultNodeConditionRules = "Ready=True:fail,OutOfDisk=False:fail,MemoryPressure=False:warn,DiskPressure=False:warn,PIDPressure=False:warn,NetworkUnavailable=False:fail"
+	DefaultNodeConditionRule  = "False:warn"
 )
 
+// NodeConditionRule describes the healthy status of a node condition and the
+// status reported when the condition is not healthy.
+type NodeConditionRule struct {
+	HealthyStatus string
+	Severity      CheckStatus
+}
+
+func (r NodeConditionRule) String() string {
+	return r.HealthyStatus + ":" + string(r.Severity)
+}
+
 type NodeChecker struct {
 	*KubernetesApi
 	*HeapsterModelApi
-	*KVClient
-	*NotifManager
-	RunWaitGroup  sync.WaitGroup
-	CheckInterval time.Duration
-	stopChannel   chan bool
-	Threshold     time.Duration
+	*CheckProcessor
+	ClusterChecker *ClusterChecker
+	RunWaitGroup   sync.WaitGroup
+	CheckInterval  time.Duration
+	stopChannel    chan bool
+	Threshold      time.Duration
+
+	ConditionRules       map[string]NodeConditionRule
+	DefaultConditionRule NodeConditionRule
+
+	CpuWarnPercent float64
+	CpuFailPercent float64
+	MemWarnPercent float64
+	MemFailPercent float64
+
+	MembershipChecks bool
+
+	usageTracker *statusTracker
+	nodes        map[string]Node
+	settingsLock sync.RWMutex
 }
 
 func (n *NodeChecker) start() {
 	logrus.Info("Starting Node Checker...")
 	n.RunWaitGroup.Add(1)
+	metrics.checkerStarted(string(CheckGroupNode))
 	n.stopChannel = make(chan bool)
-	go n.run()
+	n.usageTracker = newStatusTracker()
+	n.nodes = nil
+	go n.run(n.stopChannel)
 }
 
 func (n *NodeChecker) stop() {
 	close(n.stopChannel)
-	n.RunWaitGroup.Done()
 }
 
-func (n *NodeChecker) run() {
+// run watches the nodes and runs the node checks every check interval until stop is closed.
+func (n *NodeChecker) run(stop <-chan bool) {
+	defer n.RunWaitGroup.Done()
+	events := make(chan NodeEvent)
+	n.RunWaitGroup.Add(1)
+	go n.watchNodes(events, stop)
+
+	// node events may arrive more often than the check interval, so use a ticker to
+	// keep re-evaluating thresholds and usage
+	interval := n.checkInterval()
+	ticker := time.NewTicker(interval)
+	defer func() { ticker.Stop() }()
+
 	running := true
 	for running {
 		select {
-		case <-time.After(n.CheckInterval):
+		case event := <-events:
+			n.settingsLock.RLock()
+			n.processNodeEvent(event)
+			n.settingsLock.RUnlock()
+		case <-ticker.C:
+			n.settingsLock.RLock()
 			n.processNodeCheck()
-		case <-n.stopChannel:
+			n.settingsLock.RUnlock()
+		case <-stop:
 			running = false
 		}
-		time.Sleep(1 * time.Second)
+
+		// the check interval may have been reloaded
+		if current := n.checkInterval(); current != interval {
+			ticker.Stop()
+			interval = current
+			ticker = time.NewTicker(interval)
+		}
 	}
 }
 
-func (n *NodeChecker) processNodeCheck() {
-	logrus.Debug("Running Node Checks...")
-	nodes, err := n.Nodes()
-	if err != nil {
-		logrus.WithError(err).Error("Unable to retrieve nodes.")
-		return
-	}
-	n.processNodeCheckReady(nodes)
-	n.processNodeOutOfDisk(nodes)
-	// process Node OOD
-	// ...
+func (n *NodeChecker) checkInterval() time.Duration {
+	n.settingsLock.RLock()
+	defer n.settingsLock.RUnlock()
+	return n.CheckInterval
 }
 
-func (n *NodeChecker) processNodeCheckReady(nodes []Node) {
-	logrus.Debug("Checking Node Readiness...")
-	for _, node := range nodes {
-		ready := false
-		passThreshold := false
-		for _, condition := range node.Status.Conditions {
-			if condition.Type == ConditionTypeReady {
-				ready = condition.Status == "True"
-				duration := time.Since(condition.LastTransitionTime)
-				passThreshold = duration >= n.Threshold
+// reload replaces the check settings with the updated settings. Cached nodes and
+// usage thresholds already being tracked are kept.
+func (n *NodeChecker) reload(updated *NodeChecker) {
+	n.settingsLock.Lock()
+	defer n.settingsLock.Unlock()
+	n.CheckInterval = updated.CheckInterval
+	n.Threshold = updated.Threshold
+	n.ConditionRules = updated.ConditionRules
+	n.DefaultConditionRule = updated.DefaultConditionRule
+	n.CpuWarnPercent = updated.CpuWarnPercent
+	n.CpuFailPercent = updated.CpuFailPercent
+	n.MemWarnPercent = updated.MemWarnPercent
+	n.MemFailPercent = updated.MemFailPercent
+	n.MembershipChecks = updated.MembershipChecks
+}
+
+// watchNodes lists the nodes and then follows node changes through the watch API, relisting
+// whenever the watched resource version has expired.
+func (n *NodeChecker) watchNodes(events chan<- NodeEvent, stop <-chan bool) {
+	defer n.RunWaitGroup.Done()
+	send := func(event NodeEvent) {
+		select {
+		case events <- event:
+		case <-stop:
+		}
+	}
+
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			nodeList, err := n.NodeList()
+			if err != nil {
+				logrus.WithError(err).Error("Unable to retrieve nodes.")
+				if !n.waitForRetry(stop) {
+					return
+				}
+				continue
 			}
+			send(NodeEvent{Type: NodeEventSync, Nodes: nodeList.Items})
+			resourceVersion = nodeList.Metadata.ResourceVersion
 		}
 
-		// node readiness may have changed
-		if passThreshold {
+		logrus.Debugf("Watching nodes from resource version %s", resourceVersion)
+		lastVersion, err := n.WatchNodes(resourceVersion, stop, send)
+		select {
+		case <-stop:
+			return
+		default:
+		}
 
-			var message string
-			var status CheckStatus
-			if ready {
-				status = CheckStatusPass
-				message = node.Metadata.Name + " is Ready"
-			} else {
-				status = CheckStatusFail
-				message = node.Metadata.Name + " is NOT Ready"
+		switch {
+		case err == ErrResourceExpired:
+			logrus.Infof("Node resource version %s has expired, listing nodes again", resourceVersion)
+			resourceVersion = ""
+		case err != nil:
+			logrus.WithError(err).Warn("Node watch failed, reconnecting.")
+			resourceVersion = lastVersion
+			if !n.waitForRetry(stop) {
+				return
 			}
+		default:
+			resourceVersion = lastVersion
+		}
+	}
+}
 
-			check := KubeCheck{
-				Name:       node.Metadata.Name,
-				Node:       node.Metadata.Name,
-				CheckGroup: CheckGroupNode,
-				CheckType:  CheckTypeNodeReady,
-				Status:     status,
-				Message:    message,
-				Timestamp:  time.Now(),
-				Labels:     node.Metadata.Labels,
-			}
+// waitForRetry waits for the check interval and returns false if the checker was stopped meanwhile.
+func (n *NodeChecker) waitForRetry(stop <-chan bool) bool {
+	select {
+	case <-time.After(n.checkInterval()):
+		return true
+	case <-stop:
+		return false
+	}
+}
 
-			n.processCheck(check)
+func (n *NodeChecker) processNodeEvent(event NodeEvent) {
+	logrus.Debugf("Received node event %s", event.Type)
+	switch event.Type {
+	case NodeEventSync:
+		n.nodes = make(map[string]Node)
+		for _, node := range event.Nodes {
+			n.nodes[node.Metadata.Name] = node
+		}
+		n.processNodeConditions(event.Nodes)
+	case WatchEventAdded, WatchEventModified:
+		previous, known := n.nodes[event.Node.Metadata.Name]
+		n.nodes[event.Node.Metadata.Name] = event.Node
+		if known && sameConditionStatuses(previous, event.Node) {
+			// most updates are heartbeats, the next check cycle still checks the node
+			return
+		}
+		n.processNodeConditions([]Node{event.Node})
+	case WatchEventDeleted:
+		delete(n.nodes, event.Node.Metadata.Name)
+	default:
+		return
+	}
+	n.ClusterChecker.processNodes(n.cachedNodes())
+}
+
+// sameConditionStatuses returns true if both nodes have the same conditions with the same
+// statuses.
+func sameConditionStatuses(a, b Node) bool {
+	if len(a.Status.Conditions) != len(b.Status.Conditions) {
+		return false
+	}
+	statuses := make(map[string]string, len(a.Status.Conditions))
+	for _, condition := range a.Status.Conditions {
+		statuses[condition.Type] = condition.Status
+	}
+	for _, condition := range b.Status.Conditions {
+		if status, ok := statuses[condition.Type]; !ok || status != condition.Status {
+			return false
 		}
+	}
+	return true
+}
+
+// cachedNodes returns the nodes known from the last list and the watch events since.
+func (n *NodeChecker) cachedNodes() []Node {
+	nodes := make([]Node, 0, len(n.nodes))
+	for _, node := range n.nodes {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
 
+func (n *NodeChecker) processNodeCheck() {
+	metrics.checkCycle(string(CheckGroupNode))
+	if n.nodes == nil {
+		logrus.Debug("Nodes not listed yet, skipping Node Checks...")
+		return
 	}
+	logrus.Debug("Running Node Checks...")
+	nodes := n.cachedNodes()
+	n.processNodeConditions(nodes)
+	n.ClusterChecker.processNodes(nodes)
+	n.processNodeMembership(nodes)
+	n.processNodeCpu(nodes)
+	n.processNodeMem(nodes)
+	// with the membership checks, the removed nodes are only notified as leaving the cluster
+	n.removeStaleChecks(CheckGroupNode, func(check KubeCheck) bool {
+		_, exists := n.nodes[check.Name]
+		return exists
+	}, "removed from the cluster", !n.MembershipChecks)
 }
 
-func (n *NodeChecker) processNodeOutOfDisk(nodes []Node) {
-	logrus.Debug("Checking Node Disk Space...")
+func (n *NodeChecker) processNodeConditions(nodes []Node) {
+	logrus.Debug("Checking Node Conditions...")
 	for _, node := range nodes {
-		ok := false
-		passThreshold := false
 		for _, condition := range node.Status.Conditions {
-			if condition.Type == ConditionTypeOutOfDisk {
-				ok = condition.Status == "False"
-				duration := time.Since(condition.LastTransitionTime)
-				passThreshold = duration >= n.Threshold
+			rule, ok := n.ConditionRules[condition.Type]
+			if !ok {
+				rule = n.DefaultConditionRule
+			}
+			if rule.Severity == ConditionSeverityIgnore {
+				continue
 			}
-		}
 
-		// node ood may have changed
-		if passThreshold {
+			// node condition may have changed
+			if time.Since(condition.LastTransitionTime) < n.Threshold {
+				continue
+			}
 
-			var message string
-			var status CheckStatus
-			if ok {
-				status = CheckStatusPass
-				message = node.Metadata.Name + " has sufficient disk space"
-			} else {
-				status = CheckStatusFail
-				message = node.Metadata.Name + " is running out of disk space"
+			status := CheckStatusPass
+			message := fmt.Sprintf("%s %s is %s", node.Metadata.Name, condition.Type, condition.Status)
+			if condition.Status != rule.HealthyStatus {
+				status = rule.Severity
+				if condition.Message != "" {
+					message += ": " + condition.Message
+				}
 			}
 
 			check := KubeCheck{
 				Name:       node.Metadata.Name,
 				Node:       node.Metadata.Name,
 				CheckGroup: CheckGroupNode,
-				CheckType:  CheckTypeNodeOutOfDisk,
+				CheckType:  conditionCheckType(condition.Type),
 				Status:     status,
 				Message:    message,
 				Timestamp:  time.Now(),
@@ -148,46 +307,150 @@ func (n *NodeChecker) processNodeOutOfDisk(nodes []Node) {
 
 			n.processCheck(check)
 		}
+	}
+}
+
+func (n *NodeChecker) processNodeCpu(nodes []Node) {
+	if !n.HeapsterModelApi.Enabled() {
+		return
+	}
+	logrus.Debug("Checking Node CPU Usage...")
+	for _, node := range nodes {
+		capacity, err := parseCpuQuantity(node.Status.Capacity.Cpu)
+		if err != nil {
+			logrus.WithError(err).Warnf("unable to parse cpu capacity of %s", node.Metadata.Name)
+			continue
+		}
+		usage, err := n.NodeCpuUsage(node.Metadata.Name)
+		if err != nil {
+			logrus.WithError(err).Warnf("unable to get cpu usage of %s", node.Metadata.Name)
+			continue
+		}
+
+		percent := percentOf(usage, capacity)
+		status := n.usageStatus(percent, n.CpuWarnPercent, n.CpuFailPercent)
+		if !n.usagePassThreshold(CheckTypeNodeCpu, node.Metadata.Name, status) {
+			continue
+		}
 
+		check := KubeCheck{
+			Name:       node.Metadata.Name,
+			Node:       node.Metadata.Name,
+			CheckGroup: CheckGroupNode,
+			CheckType:  CheckTypeNodeCpu,
+			Status:     status,
+			Message:    fmt.Sprintf("%s cpu usage is %.0f%% (%dm of %dm)", node.Metadata.Name, percent, usage, capacity),
+			Timestamp:  time.Now(),
+			Labels:     node.Metadata.Labels,
+		}
+
+		n.processCheck(check)
 	}
 }
 
-func (n *NodeChecker) processCheck(check KubeCheck) {
-	exists, err := n.checkExists(check)
-	if err != nil {
-		logrus.WithError(err).Error("unable to determine if check exists or not")
+func (n *NodeChecker) processNodeMem(nodes []Node) {
+	if !n.HeapsterModelApi.Enabled() {
 		return
 	}
-	if !exists {
-		logrus.Infof("check %s is not in the record. recoding now", check.Name)
-		err := n.saveCheck(check)
+	logrus.Debug("Checking Node Memory Usage...")
+	for _, node := range nodes {
+		capacity, err := parseMemoryQuantity(node.Status.Capacity.Memory)
 		if err != nil {
-			logrus.WithError(err).Warnf("Unable to save check")
-			return
+			logrus.WithError(err).Warnf("unable to parse memory capacity of %s", node.Metadata.Name)
+			continue
+		}
+		usage, err := n.NodeMemoryWorkingSet(node.Metadata.Name)
+		if err != nil {
+			logrus.WithError(err).Warnf("unable to get memory usage of %s", node.Metadata.Name)
+			continue
 		}
-		if check.Status == CheckStatusFail {
-			logrus.Info("check %s is new and failing, will notify", check.Name)
-			n.addNotification(check)
+
+		percent := percentOf(usage, capacity)
+		status := n.usageStatus(percent, n.MemWarnPercent, n.MemFailPercent)
+		if !n.usagePassThreshold(CheckTypeNodeMem, node.Metadata.Name, status) {
+			continue
 		}
-	} else {
-		oldCheck, err := n.getCheck(check.CheckGroup, check.CheckType, check.Name)
+
+		check := KubeCheck{
+			Name:       node.Metadata.Name,
+			Node:       node.Metadata.Name,
+			CheckGroup: CheckGroupNode,
+			CheckType:  CheckTypeNodeMem,
+			Status:     status,
+			Message:    fmt.Sprintf("%s memory usage is %.0f%% (%dMi of %dMi)", node.Metadata.Name, percent, usage>>20, capacity>>20),
+			Timestamp:  time.Now(),
+			Labels:     node.Metadata.Labels,
+		}
+
+		n.processCheck(check)
+	}
+}
+
+func (n *NodeChecker) usageStatus(percent, warn, fail float64) CheckStatus {
+	switch {
+	case fail > 0 && percent >= fail:
+		return CheckStatusFail
+	case warn > 0 && percent >= warn:
+		return CheckStatusWarn
+	default:
+		return CheckStatusPass
+	}
+}
+
+// usagePassThreshold returns true once a usage status has been held for the check threshold.
+func (n *NodeChecker) usagePassThreshold(checkType KubeCheckType, name string, status CheckStatus) bool {
+	return n.usageTracker.held(string(checkType)+"/"+name, status, n.Threshold)
+}
+
+// parseNodeConditionRules parses a comma separated list of Type=HealthyStatus:severity rules.
+func parseNodeConditionRules(rules string) (map[string]NodeConditionRule, error) {
+	conditionRules := make(map[string]NodeConditionRule)
+	for _, entry := range strings.Split(rules, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid node condition rule %q, expected Type=HealthyStatus:severity", entry)
+		}
+		rule, err := parseNodeConditionRule(parts[1])
 		if err != nil {
-			logrus.WithError(err).Warnf("unable to get previous check, can't proceed")
-			return
+			return nil, err
 		}
-		logrus.Printf("old: %s, new: %s", oldCheck.Status, check.Status)
-		if check.Status != oldCheck.Status {
-			logrus.Debugf("check %s status has changed, will notify", check.Name)
-			logrus.Debugf("status for %s:%s:%s has changed.", check.CheckGroup, check.CheckType, check.Name)
-			err := n.saveCheck(check)
-			if err != nil {
-				logrus.WithError(err).Warnf("Unable to save")
-				return
+		conditionRules[parts[0]] = rule
+	}
+	return conditionRules, nil
+}
+
+// parseNodeConditionRule parses a single HealthyStatus:severity rule.
+func parseNodeConditionRule(rule string) (NodeConditionRule, error) {
+	parts := strings.SplitN(rule, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return NodeConditionRule{}, fmt.Errorf("invalid node condition rule %q, expected HealthyStatus:severity", rule)
+	}
+	severity := CheckStatus(parts[1])
+	switch severity {
+	case CheckStatusWarn, CheckStatusFail, ConditionSeverityIgnore:
+	default:
+		return NodeConditionRule{}, fmt.Errorf("invalid node condition severity %q, expected warn, fail or ignore", parts[1])
+	}
+	return NodeConditionRule{HealthyStatus: parts[0], Severity: severity}, nil
+}
+
+// conditionCheckType derives the check type of a node condition, e.g. OutOfDisk becomes node-out-of-disk.
+func conditionCheckType(conditionType string) KubeCheckType {
+	runes := []rune(conditionType)
+	var name []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				name = append(name, '-')
 			}
-			logrus.Infof("check %s is failing, will notify", check.Name)
-			n.addNotification(check)
-		} else {
-			logrus.Debug("nothing has changed.")
 		}
+		name = append(name, unicode.ToLower(r))
 	}
+	return KubeCheckType("node-" + string(name))
 }
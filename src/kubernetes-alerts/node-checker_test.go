@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNodeConditionRules(t *testing.T) {
+	tests := []struct {
+		rules    string
+		expected map[string]NodeConditionRule
+		err      bool
+	}{
+		{"", map[string]NodeConditionRule{}, false},
+		{
+			"Ready=True:fail",
+			map[string]NodeConditionRule{"Ready": {"True", CheckStatusFail}},
+			false,
+		},
+		{
+			"Ready=True:fail, MemoryPressure=False:warn,,NetworkUnavailable=False:ignore",
+			map[string]NodeConditionRule{
+				"Ready":              {"True", CheckStatusFail},
+				"MemoryPressure":     {"False", CheckStatusWarn},
+				"NetworkUnavailable": {"False", ConditionSeverityIgnore},
+			},
+			false,
+		},
+		{"Ready", nil, true},
+		{"=True:fail", nil, true},
+		{"Ready=True", nil, true},
+		{"Ready=:fail", nil, true},
+		{"Ready=True:critical", nil, true},
+		{"Ready=True:pass", nil, true},
+	}
+	for _, test := range tests {
+		rules, err := parseNodeConditionRules(test.rules)
+		if test.err {
+			assert.Error(t, err, test.rules)
+			continue
+		}
+		assert.NoError(t, err, test.rules)
+		assert.Equal(t, test.expected, rules, test.rules)
+	}
+}
+
+func TestConditionCheckType(t *testing.T) {
+	tests := []struct {
+		conditionType string
+		expected      KubeCheckType
+	}{
+		{"Ready", "node-ready"},
+		{"OutOfDisk", "node-out-of-disk"},
+		{"MemoryPressure", "node-memory-pressure"},
+		{"PIDPressure", "node-pid-pressure"},
+		{"KernelDeadlock", "node-kernel-deadlock"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, conditionCheckType(test.conditionType))
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+const MembersKey = "kube-alerts-members"
+
+// clusterMember is a node known to be part of the cluster.
+type clusterMember struct {
+	Joined time.Time         `json:"joined"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// processNodeMembership compares the nodes with the members recorded in the KV store and notifies
+// the nodes that joined or left the cluster since. Membership changes are notified once and are
+// not recorded as checks. The current nodes are recorded silently the first time.
+func (n *NodeChecker) processNodeMembership(nodes []Node) {
+	if !n.MembershipChecks {
+		return
+	}
+	logrus.Debug("Checking Node Membership...")
+	var members map[string]clusterMember
+	err := n.getValue(MembersKey, &members)
+	if err != nil && err != store.ErrKeyNotFound {
+		logrus.WithError(err).Warn("unable to get the cluster members")
+		return
+	}
+	first := err == store.ErrKeyNotFound
+	if members == nil {
+		members = make(map[string]clusterMember)
+	}
+
+	now := time.Now()
+	changed := false
+	current := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		name := node.Metadata.Name
+		current[name] = true
+		if _, ok := members[name]; ok {
+			continue
+		}
+		members[name] = clusterMember{Joined: now, Labels: node.Metadata.Labels}
+		changed = true
+		if !first {
+			n.notifyMembership(name, node.Metadata.Labels, CheckStatusPass, "joined the cluster")
+		}
+	}
+	for _, name := range memberNames(members) {
+		if current[name] {
+			continue
+		}
+		n.notifyMembership(name, members[name].Labels, CheckStatusWarn, "left the cluster")
+		delete(members, name)
+		changed = true
+	}
+
+	if first {
+		logrus.Infof("Recording %d cluster members", len(members))
+	}
+	if changed || first {
+		if err := n.putValue(MembersKey, members); err != nil {
+			logrus.WithError(err).Warn("unable to save the cluster members")
+		}
+	}
+}
+
+func (n *NodeChecker) notifyMembership(name string, labels map[string]string, status CheckStatus, change string) {
+	logrus.Infof("node %s %s, will notify", name, change)
+	n.addNotification(KubeCheck{
+		Name:       name,
+		Node:       name,
+		CheckGroup: CheckGroupNode,
+		CheckType:  CheckTypeNodeMembership,
+		Status:     status,
+		Message:    fmt.Sprintf("%s %s", name, change),
+		Timestamp:  time.Now(),
+		Labels:     labels,
+	})
+}
+
+// memberNames returns the names of the members, sorted.
+func memberNames(members map[string]clusterMember) []string {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
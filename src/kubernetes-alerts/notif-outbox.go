@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"encoding/json"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	OutboxPrefix     = "kube-alerts-outbox"
+	DeadLetterPrefix = "kube-alerts-deadletter"
+)
+
+// PendingNotif is a notification that failed to be delivered and is waiting to be retried. The
+// URLs are the ones that failed for a UrlNotifier, empty to retry the whole notifier.
+type PendingNotif struct {
+	ID          string      `json:"id"`
+	Notifier    string      `json:"notifier"`
+	Urls        []string    `json:"urls,omitempty"`
+	Checks      []KubeCheck `json:"checks"`
+	Attempts    int         `json:"attempts"`
+	Created     time.Time   `json:"created"`
+	NextAttempt time.Time   `json:"nextAttempt"`
+}
+
+func outboxKey(notifier, id string) string {
+	return fmt.Sprintf("%s/%s/%s", OutboxPrefix, notifier, id)
+}
+
+func deadLetterKey(notifier, id string) string {
+	return fmt.Sprintf("%s/%s/%s", DeadLetterPrefix, notifier, id)
+}
+
+// queueRetry stores a failed notification in the outbox so it is retried, even after a restart.
+func (n *NotifManager) queueRetry(notifier Notifier, checks []KubeCheck, urls []string) {
+	now := time.Now()
+	pending := PendingNotif{
+		ID:          fmt.Sprintf("%d", now.UnixNano()),
+		Notifier:    notifier.NotifName(),
+		Urls:        urls,
+		Checks:      checks,
+		Attempts:    1,
+		Created:     now,
+		NextAttempt: now.Add(n.retryBackoff(1)),
+	}
+	logrus.Warnf("Unable to notify %s, will retry at %s", pending.Notifier, pending.NextAttempt)
+	if err := n.putValue(outboxKey(pending.Notifier, pending.ID), pending); err != nil {
+		logrus.WithError(err).Errorf("Unable to store failed notification for %s, %d checks are lost", pending.Notifier, len(checks))
+	}
+}
+
+// retryNotifications resends the pending notifications that are due.
+func (n *NotifManager) retryNotifications() {
+	kvpairs, err := n.listValues(OutboxPrefix)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to list pending notifications")
+		return
+	}
+	now := time.Now()
+	for _, kvpair := range kvpairs {
+		var pending PendingNotif
+		if err := json.Unmarshal(kvpair.Value, &pending); err != nil {
+			logrus.WithError(err).Errorf("Unable to read pending notification %s", kvpair.Key)
+			continue
+		}
+		if pending.NextAttempt.After(now) {
+			continue
+		}
+
+		notifier := n.notifier(pending.Notifier)
+		if notifier == nil {
+			logrus.Warnf("Notifier %s no longer exists", pending.Notifier)
+			n.deadLetter(kvpair.Key, pending)
+			continue
+		}
+		if !notifier.NotifEnabled() {
+			continue
+		}
+
+		logrus.Infof("Retrying notification %s to %s (attempt %d)", pending.ID, pending.Notifier, pending.Attempts+1)
+		var sent bool
+		if urlNotifier, ok := notifier.(UrlNotifier); ok && len(pending.Urls) > 0 {
+			// the URLs removed from the notifier since are not retried
+			pending.Urls = urlNotifier.NotifyUrls(pending.Checks, intersectStrings(pending.Urls, urlNotifier.NotifUrls()))
+			sent = len(pending.Urls) == 0
+		} else {
+			sent = notifier.Notify(pending.Checks)
+		}
+		metrics.notification(notifier.NotifName(), sent)
+		if sent {
+			if err := n.deleteKey(kvpair.Key); err != nil {
+				logrus.WithError(err).Errorf("Unable to remove delivered notification %s", kvpair.Key)
+			}
+			continue
+		}
+
+		pending.Attempts++
+		if n.MaxAttempts > 0 && pending.Attempts >= n.MaxAttempts {
+			logrus.Errorf("Giving up on notification %s to %s after %d attempts", pending.ID, pending.Notifier, pending.Attempts)
+			n.deadLetter(kvpair.Key, pending)
+			continue
+		}
+		pending.NextAttempt = now.Add(n.retryBackoff(pending.Attempts))
+		if err := n.putValue(kvpair.Key, pending); err != nil {
+			logrus.WithError(err).Errorf("Unable to update pending notification %s", kvpair.Key)
+		}
+	}
+}
+
+// deadLetter moves a pending notification out of the outbox so it can be inspected later.
+func (n *NotifManager) deadLetter(key string, pending PendingNotif) {
+	if err := n.putValue(deadLetterKey(pending.Notifier, pending.ID), pending); err != nil {
+		logrus.WithError(err).Errorf("Unable to store dead notification %s", pending.ID)
+		return
+	}
+	if err := n.deleteKey(key); err != nil {
+		logrus.WithError(err).Errorf("Unable to remove dead notification %s from the outbox", key)
+	}
+}
+
+// retryBackoff doubles the retry interval for every attempt, up to the max retry interval.
+func (n *NotifManager) retryBackoff(attempts int) time.Duration {
+	backoff := n.RetryInterval
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if n.MaxRetryInterval > 0 && backoff >= n.MaxRetryInterval {
+			return n.MaxRetryInterval
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNotifier struct {
+	sent     bool
+	notified [][]KubeCheck
+}
+
+func (n *testNotifier) Notify(checks []KubeCheck) bool {
+	n.notified = append(n.notified, checks)
+	return n.sent
+}
+
+func (n *testNotifier) NotifEnabled() bool { return true }
+func (n *testNotifier) NotifName() string  { return "test" }
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts         int
+		maxRetryInterval time.Duration
+		expected         time.Duration
+	}{
+		{1, 10 * time.Minute, time.Minute},
+		{2, 10 * time.Minute, 2 * time.Minute},
+		{3, 10 * time.Minute, 4 * time.Minute},
+		{4, 10 * time.Minute, 8 * time.Minute},
+		{5, 10 * time.Minute, 10 * time.Minute},
+		{50, 10 * time.Minute, 10 * time.Minute},
+		{5, 0, 16 * time.Minute},
+	}
+	for _, test := range tests {
+		n := &NotifManager{RetryInterval: time.Minute, MaxRetryInterval: test.maxRetryInterval}
+		assert.Equal(t, test.expected, n.retryBackoff(test.attempts), test)
+	}
+}
+
+// pendingNotifs returns the pending notifications below prefix, making them due when due is set.
+func pendingNotifs(t *testing.T, n *NotifManager, prefix string, due bool) []PendingNotif {
+	kvpairs, err := n.listValues(prefix)
+	assert.NoError(t, err)
+	pendings := make([]PendingNotif, 0, len(kvpairs))
+	for _, kvpair := range kvpairs {
+		var pending PendingNotif
+		assert.NoError(t, json.Unmarshal(kvpair.Value, &pending))
+		if due {
+			pending.NextAttempt = time.Now().Add(-time.Second)
+			assert.NoError(t, n.putValue(kvpair.Key, pending))
+		}
+		pendings = append(pendings, pending)
+	}
+	return pendings
+}
+
+func TestRetryNotifications(t *testing.T) {
+	notifier := &testNotifier{}
+	n := &NotifManager{
+		KVClient:      &KVClient{store: newMemoryStore()},
+		Notifiers:     []Notifier{notifier},
+		RetryInterval: time.Hour,
+		MaxAttempts:   3,
+	}
+	checks := []KubeCheck{{Name: "node-1", CheckGroup: CheckGroupNode, CheckType: "node-ready", Status: CheckStatusFail}}
+
+	n.queueRetry(notifier, checks, nil)
+	pending := pendingNotifs(t, n, OutboxPrefix, false)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, checks, pending[0].Checks)
+
+	// not due yet
+	n.retryNotifications()
+	assert.Len(t, notifier.notified, 0)
+
+	// failed again, retried later
+	pendingNotifs(t, n, OutboxPrefix, true)
+	n.retryNotifications()
+	assert.Len(t, notifier.notified, 1)
+	pending = pendingNotifs(t, n, OutboxPrefix, false)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].Attempts)
+	assert.True(t, pending[0].NextAttempt.After(time.Now().Add(time.Hour)))
+
+	// failed on the last attempt, moved to the dead letters
+	pendingNotifs(t, n, OutboxPrefix, true)
+	n.retryNotifications()
+	assert.Len(t, notifier.notified, 2)
+	assert.Len(t, pendingNotifs(t, n, OutboxPrefix, false), 0)
+	dead := pendingNotifs(t, n, DeadLetterPrefix, false)
+	assert.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Equal(t, checks, dead[0].Checks)
+
+	// delivered, removed from the outbox
+	n.queueRetry(notifier, checks, nil)
+	notifier.sent = true
+	pendingNotifs(t, n, OutboxPrefix, true)
+	n.retryNotifications()
+	assert.Len(t, notifier.notified, 3)
+	assert.Len(t, pendingNotifs(t, n, OutboxPrefix, false), 0)
+	assert.Len(t, pendingNotifs(t, n, DeadLetterPrefix, false), 1)
+}
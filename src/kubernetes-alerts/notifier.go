@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,45 +12,105 @@ import (
 type Notifier interface {
 	Notify(checks []KubeCheck) bool
 	NotifEnabled() bool
+	NotifName() string
+}
+
+// UrlNotifier is a notifier that sends to several URLs. Each URL is delivered, and retried,
+// on its own so a failing URL does not resend the notifications to the others.
+type UrlNotifier interface {
+	Notifier
+	NotifUrls() []string
+	// NotifyUrls sends the checks to the urls and returns the urls that failed.
+	NotifyUrls(checks []KubeCheck, urls []string) []string
 }
 
 type NotifManager struct {
+	*KVClient
 	NotifInterval      time.Duration
 	Notifiers          []Notifier
+	Routes             []*NotifRoute
+	RetryInterval      time.Duration
+	MaxRetryInterval   time.Duration
+	MaxAttempts        int
+	RemindFailInterval time.Duration
+	RemindWarnInterval time.Duration
 	notifChannel       chan KubeCheck
 	stopChannel        chan bool
+	doneChannel        chan bool
 	checks             []KubeCheck
+	lastNotified       map[string]time.Time
+	notifiedChanged    bool
 	addCheckWaitGroup  sync.WaitGroup
 	sendNotifWaitGroup sync.WaitGroup
+	settingsLock       sync.RWMutex
+	channelLock        sync.RWMutex
 }
 
+// Start starts listening for checks. The notif manager can be started again after Stop, e.g.
+// when the leadership is regained.
 func (n *NotifManager) Start() {
 	logrus.Info("Starting notif manager...")
+	if n.doneChannel != nil {
+		// the previous run may still be sending the notifications pending when it was stopped
+		<-n.doneChannel
+	}
+	n.channelLock.Lock()
+	defer n.channelLock.Unlock()
 	n.notifChannel = make(chan KubeCheck, 10)
 	n.stopChannel = make(chan bool)
+	n.doneChannel = make(chan bool)
 	n.checks = make([]KubeCheck, 0)
-	go n.listenForNotif()
+	n.lastNotified = make(map[string]time.Time)
+	go n.listenForNotif(n.notifChannel, n.stopChannel, n.doneChannel)
 }
 
-func (n *NotifManager) Stop() {
-	n.stopChannel <- true
-	close(n.stopChannel)
-	close(n.notifChannel)
+// Stop stops listening for checks and sends the pending notifications. It returns after the
+// timeout even if the notifications are not sent yet. The checkers must be stopped first.
+func (n *NotifManager) Stop(timeout time.Duration) {
+	logrus.Info("Stopping notif manager...")
+	_, stopChannel := n.channels()
+	close(stopChannel)
+	select {
+	case <-n.doneChannel:
+	case <-time.After(timeout):
+		logrus.Warnf("Pending notifications were not sent within %s", timeout)
+	}
 }
 
-func (n *NotifManager) listenForNotif() {
+// channels returns the channels of the current run, nil if the notif manager was never started.
+func (n *NotifManager) channels() (chan KubeCheck, chan bool) {
+	n.channelLock.RLock()
+	defer n.channelLock.RUnlock()
+	return n.notifChannel, n.stopChannel
+}
+
+// running returns true if the notif manager is started and not stopped.
+func (n *NotifManager) running() bool {
+	_, stopChannel := n.channels()
+	return stopChannel != nil && !stopped(stopChannel)
+}
+
+func (n *NotifManager) listenForNotif(notifChannel chan KubeCheck, stopChannel, doneChannel chan bool) {
+	defer close(doneChannel)
+	n.loadLastNotified()
 	running := true
 	for running {
 		select {
-		case <-n.stopChannel:
+		case <-stopChannel:
 			running = false
-		case <-time.After(n.NotifInterval):
+			n.flushNotifications(notifChannel)
+		case <-time.After(n.notifInterval()):
 			logrus.Debug("Trying to send notifications...")
 			n.addCheckWaitGroup.Wait()
 			n.sendNotifWaitGroup.Add(1)
+			n.settingsLock.RLock()
+			n.retryNotifications()
+			n.expireSilences()
 			n.sendNotifications()
+			n.sendReminders()
+			n.settingsLock.RUnlock()
 			n.sendNotifWaitGroup.Done()
-		case check := <-n.notifChannel:
+		case check := <-notifChannel:
 			logrus.Debug("Adding check for notification...")
 			n.sendNotifWaitGroup.Wait()
 			n.addCheckWaitGroup.Add(1)
@@ -58,21 +120,228 @@ func (n *NotifManager) listenForNotif() {
 	}
 }
 
+// flushNotifications sends the checks still waiting in the channel along with the batched checks.
+func (n *NotifManager) flushNotifications(notifChannel chan KubeCheck) {
+	for {
+		select {
+		case check := <-notifChannel:
+			n.checks = append(n.checks, check)
+		default:
+			logrus.Infof("Sending %d pending notifications...", len(n.checks))
+			n.settingsLock.RLock()
+			n.sendNotifications()
+			n.settingsLock.RUnlock()
+			return
+		}
+	}
+}
+
+// queueDepth returns the number of checks waiting in the notification channel.
+func (n *NotifManager) queueDepth() int {
+	notifChannel, _ := n.channels()
+	return len(notifChannel)
+}
+
+func (n *NotifManager) notifInterval() time.Duration {
+	n.settingsLock.RLock()
+	defer n.settingsLock.RUnlock()
+	return n.NotifInterval
+}
+
+// reload replaces the notifiers, routes and intervals with the updated settings. Pending
+// notifications are kept.
+func (n *NotifManager) reload(updated *NotifManager) {
+	n.settingsLock.Lock()
+	defer n.settingsLock.Unlock()
+	n.NotifInterval = updated.NotifInterval
+	n.Notifiers = updated.Notifiers
+	n.Routes = updated.Routes
+	n.RetryInterval = updated.RetryInterval
+	n.MaxRetryInterval = updated.MaxRetryInterval
+	n.MaxAttempts = updated.MaxAttempts
+	n.RemindFailInterval = updated.RemindFailInterval
+	n.RemindWarnInterval = updated.RemindWarnInterval
+}
+
+func (n *NotifManager) notifier(name string) Notifier {
+	for _, notifier := range n.Notifiers {
+		if notifier.NotifName() == name {
+			return notifier
+		}
+	}
+	return nil
+}
+
+// addNotification queues a check to be notified. The check is dropped if the notif manager is
+// not running, e.g. on a standby replica.
 func (n *NotifManager) addNotification(check KubeCheck) {
-	n.notifChannel <- check
+	notifChannel, stopChannel := n.channels()
+	if notifChannel == nil {
+		logrus.Warnf("notif manager is not running, check %s is not notified", check.Name)
+		return
+	}
+	select {
+	case notifChannel <- check:
+	case <-stopChannel:
+		logrus.Warnf("notif manager is stopped, check %s is not notified", check.Name)
+	}
+}
+
+// NotifRoute sends the checks matching all of its matchers to its notifiers. An empty
+// matcher matches every check.
+type NotifRoute struct {
+	Groups    []string
+	Types     []string
+	Statuses  []string
+	Labels    map[string]string
+	Notifiers []Notifier
+	Reminders map[CheckStatus]time.Duration
 }
 
 func (n *NotifManager) sendNotifications() {
+	if len(n.checks) > 0 {
+		n.checks = n.removeSilenced(n.checks)
+	}
 	if len(n.checks) > 0 {
 		for _, notifier := range n.Notifiers {
-			if notifier.NotifEnabled() {
-				notifier.Notify(n.checks)
+			if !notifier.NotifEnabled() {
+				continue
+			}
+			if checks := n.routeChecks(notifier, n.checks); len(checks) > 0 {
+				n.notify(notifier, checks)
+				n.notified(notifier, checks, time.Now())
 			}
 		}
 		n.checks = make([]KubeCheck, 0)
 	}
 }
 
+// notify sends the checks to the notifier and queues them for a retry if they are not sent.
+// Only the failed URLs of a UrlNotifier are retried.
+func (n *NotifManager) notify(notifier Notifier, checks []KubeCheck) {
+	var sent bool
+	var failed []string
+	if urlNotifier, ok := notifier.(UrlNotifier); ok {
+		failed = urlNotifier.NotifyUrls(checks, urlNotifier.NotifUrls())
+		sent = len(failed) == 0
+	} else {
+		sent = notifier.Notify(checks)
+	}
+	metrics.notification(notifier.NotifName(), sent)
+	if !sent {
+		n.queueRetry(notifier, checks, failed)
+	}
+}
+
+// routeChecks returns the checks to be sent to the notifier. Notifiers that are not part of
+// any route receive every check.
+func (n *NotifManager) routeChecks(notifier Notifier, checks []KubeCheck) []KubeCheck {
+	routes := make([]*NotifRoute, 0)
+	for _, route := range n.Routes {
+		if route.hasNotifier(notifier) {
+			routes = append(routes, route)
+		}
+	}
+	if len(routes) == 0 {
+		return checks
+	}
+
+	routed := make([]KubeCheck, 0)
+	for _, check := range checks {
+		for _, route := range routes {
+			if route.matches(check) {
+				routed = append(routed, check)
+				break
+			}
+		}
+	}
+	return routed
+}
+
+func (r *NotifRoute) hasNotifier(notifier Notifier) bool {
+	for _, n := range r.Notifiers {
+		if n == notifier {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *NotifRoute) matches(check KubeCheck) bool {
+	if len(r.Groups) > 0 && !containsString(string(check.CheckGroup), r.Groups) {
+		return false
+	}
+	if len(r.Types) > 0 && !containsString(string(check.CheckType), r.Types) {
+		return false
+	}
+	if len(r.Statuses) > 0 && !containsString(string(check.Status), r.Statuses) {
+		return false
+	}
+	for key, value := range r.Labels {
+		if check.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNotifRoute parses a route in the form of
+// notifiers=slack,email;groups=node;types=node-ready;statuses=fail;labels=namespace=payments
+func parseNotifRoute(spec string, notifiers map[string]Notifier) (*NotifRoute, error) {
+	route := &NotifRoute{Labels: make(map[string]string)}
+	for _, field := range strings.Split(spec, ";") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid route field %q", field)
+		}
+		values := splitList(parts[1])
+		switch parts[0] {
+		case "notifiers":
+			for _, name := range values {
+				notifier, ok := notifiers[name]
+				if !ok {
+					return nil, fmt.Errorf("unknown notifier %q in route", name)
+				}
+				route.Notifiers = append(route.Notifiers, notifier)
+			}
+		case "groups":
+			route.Groups = values
+		case "types":
+			route.Types = values
+		case "statuses":
+			route.Statuses = values
+		case "labels":
+			for _, value := range values {
+				label := strings.SplitN(value, "=", 2)
+				if len(label) != 2 {
+					return nil, fmt.Errorf("invalid route label %q, expected key=value", value)
+				}
+				route.Labels[label[0]] = label[1]
+			}
+		case "reminders":
+			reminders, err := parseReminders(values)
+			if err != nil {
+				return nil, err
+			}
+			route.Reminders = reminders
+		default:
+			return nil, fmt.Errorf("unknown route field %q", parts[0])
+		}
+	}
+	if len(route.Notifiers) == 0 {
+		return nil, fmt.Errorf("route %q has no notifiers", spec)
+	}
+	return route, nil
+}
+
+// checkMessage returns the message of a check, with its acknowledgement if any.
+func checkMessage(check KubeCheck) string {
+	if check.Acknowledgement != nil {
+		return fmt.Sprintf("%s (%s)", check.Message, check.Acknowledgement)
+	}
+	return check.Message
+}
+
 func NotifSummary(checks []KubeCheck) (overall CheckStatus, pass, warn, fail int) {
 	overall = CheckStatusPass
 	for _, check := range checks {
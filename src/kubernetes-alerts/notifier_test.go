@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotifRoute(t *testing.T) {
+	slack := &SlackNotifier{}
+	email := &EmailNotifier{}
+	notifiers := map[string]Notifier{"slack": slack, "email": email}
+
+	tests := []struct {
+		spec     string
+		expected *NotifRoute
+		err      bool
+	}{
+		{
+			"notifiers=slack",
+			&NotifRoute{Notifiers: []Notifier{slack}, Labels: map[string]string{}},
+			false,
+		},
+		{
+			"notifiers=slack,email;groups=node;types=node-ready,node-out-of-disk;statuses=fail",
+			&NotifRoute{
+				Notifiers: []Notifier{slack, email},
+				Groups:    []string{"node"},
+				Types:     []string{"node-ready", "node-out-of-disk"},
+				Statuses:  []string{"fail"},
+				Labels:    map[string]string{},
+			},
+			false,
+		},
+		{
+			"notifiers=email;labels=namespace=payments,team=core",
+			&NotifRoute{
+				Notifiers: []Notifier{email},
+				Labels:    map[string]string{"namespace": "payments", "team": "core"},
+			},
+			false,
+		},
+		{"", nil, true},
+		{"groups=node", nil, true},
+		{"notifiers=pagerduty", nil, true},
+		{"notifiers=slack;groups", nil, true},
+		{"notifiers=slack;owners=me", nil, true},
+		{"notifiers=slack;labels=namespace", nil, true},
+	}
+	for _, test := range tests {
+		route, err := parseNotifRoute(test.spec, notifiers)
+		if test.err {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.expected, route, test.spec)
+	}
+}
+
+func TestNotifRouteMatches(t *testing.T) {
+	check := KubeCheck{
+		CheckGroup: CheckGroupNode,
+		CheckType:  "node-ready",
+		Status:     CheckStatusFail,
+		Labels:     map[string]string{"namespace": "payments", "team": "core"},
+	}
+
+	tests := []struct {
+		route    NotifRoute
+		expected bool
+	}{
+		{NotifRoute{}, true},
+		{NotifRoute{Groups: []string{"pod", "node"}}, true},
+		{NotifRoute{Groups: []string{"pod"}}, false},
+		{NotifRoute{Types: []string{"node-ready"}, Statuses: []string{"warn", "fail"}}, true},
+		{NotifRoute{Types: []string{"node-out-of-disk"}}, false},
+		{NotifRoute{Statuses: []string{"warn"}}, false},
+		{NotifRoute{Labels: map[string]string{"namespace": "payments"}}, true},
+		{NotifRoute{Labels: map[string]string{"namespace": "payments", "team": "web"}}, false},
+		{NotifRoute{Labels: map[string]string{"owner": ""}}, true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.route.matches(check), test.route)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	PagerDutyEventsUrl = "https://events.pagerduty.com/v2/enqueue"
+
+	PagerDutyActionTrigger = "trigger"
+	PagerDutyActionResolve = "resolve"
+	PagerDutyActionAck     = "acknowledge"
+
+	PagerDutyTimeout = 10 * time.Second
+)
+
+var pagerDutyClient = &http.Client{Timeout: PagerDutyTimeout}
+
+type PagerDutyNotifier struct {
+	Name        string
+	Enabled     bool
+	ClusterName string
+	RoutingKey  string
+	Url         string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string           `json:"summary"`
+	Source        string           `json:"source"`
+	Severity      string           `json:"severity"`
+	Timestamp     string           `json:"timestamp"`
+	Component     string           `json:"component,omitempty"`
+	Group         string           `json:"group"`
+	Class         string           `json:"class"`
+	CustomDetails pagerDutyDetails `json:"custom_details"`
+}
+
+// pagerDutyDetails are the custom details of an incident. The labels are kept apart so they
+// cannot overwrite the other details.
+type pagerDutyDetails struct {
+	Cluster string            `json:"cluster"`
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Notify triggers an incident for every warning or failing check and resolves the incident
+// of every passing check. Incidents are deduplicated by the KV key of the check.
+func (pd *PagerDutyNotifier) Notify(checks []KubeCheck) bool {
+	logrus.Infof("Sending %d events to PagerDuty", len(checks))
+
+	sent := true
+	for _, check := range checks {
+		if err := pd.sendEvent(pd.event(check)); err != nil {
+			logrus.WithError(err).Errorf("Unable to send PagerDuty event for %s", check.Name)
+			sent = false
+		}
+	}
+	if sent {
+		logrus.Info("PagerDuty events sent.")
+	}
+	return sent
+}
+
+func (pd *PagerDutyNotifier) NotifEnabled() bool {
+	return pd.Enabled
+}
+
+func (pd *PagerDutyNotifier) NotifName() string {
+	return pd.Name
+}
+
+func (pd *PagerDutyNotifier) event(check KubeCheck) pagerDutyEvent {
+	event := pagerDutyEvent{
+		RoutingKey: pd.RoutingKey,
+		DedupKey:   checkKey(check.CheckGroup, check.CheckType, check.Name),
+	}
+	if check.Status == CheckStatusPass {
+		event.EventAction = PagerDutyActionResolve
+		return event
+	}
+	if check.Acknowledgement != nil {
+		event.EventAction = PagerDutyActionAck
+		return event
+	}
+
+	source := check.Node
+	if source == "" {
+		source = pd.ClusterName
+	}
+	event.EventAction = PagerDutyActionTrigger
+	event.Payload = &pagerDutyPayload{
+		Summary:   fmt.Sprintf("[%s] %s", pd.ClusterName, check.Message),
+		Source:    source,
+		Severity:  pagerDutySeverity(check.Status),
+		Timestamp: check.Timestamp.Format(time.RFC3339),
+		Component: check.Node,
+		Group:     string(check.CheckGroup),
+		Class:     string(check.CheckType),
+		CustomDetails: pagerDutyDetails{
+			Cluster: pd.ClusterName,
+			Name:    check.Name,
+			Status:  string(check.Status),
+			Message: check.Message,
+			Labels:  check.Labels,
+		},
+	}
+	return event
+}
+
+func pagerDutySeverity(status CheckStatus) string {
+	switch status {
+	case CheckStatusFail:
+		return "critical"
+	case CheckStatusWarn:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func (pd *PagerDutyNotifier) sendEvent(event pagerDutyEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	url := pd.Url
+	if url == "" {
+		url = PagerDutyEventsUrl
+	}
+	res, err := pagerDutyClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, string(body))
+	}
+	return nil
+}
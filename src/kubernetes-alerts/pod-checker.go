@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	WaitingReasonCrashLoop    = "CrashLoopBackOff"
+	WaitingReasonImagePull    = "ImagePullBackOff"
+	WaitingReasonErrImagePull = "ErrImagePull"
+
+	LabelNamespace = "namespace"
+)
+
+type PodChecker struct {
+	*KubernetesApi
+	*CheckProcessor
+	Enabled       bool
+	RunWaitGroup  sync.WaitGroup
+	CheckInterval time.Duration
+	Threshold     time.Duration
+	RestartLimit  int
+	RestartWindow time.Duration
+	stopChannel   chan bool
+	settingsLock  sync.RWMutex
+
+	waitingTracker *statusTracker
+	restarts       map[string][]restartSample
+	// recorded maps the keys of the pod checks recorded in the KV store to their container,
+	// nil until they are listed
+	recorded map[string]string
+}
+
+type restartSample struct {
+	count     int
+	timestamp time.Time
+}
+
+func (p *PodChecker) start() {
+	logrus.Info("Starting Pod Checker...")
+	p.RunWaitGroup.Add(1)
+	metrics.checkerStarted(string(CheckGroupPod))
+	p.stopChannel = make(chan bool)
+	p.waitingTracker = newStatusTracker()
+	p.restarts = make(map[string][]restartSample)
+	p.recorded = nil
+	go p.run(p.stopChannel)
+}
+
+func (p *PodChecker) stop() {
+	close(p.stopChannel)
+}
+
+// run lists the pods and checks their containers every check interval until stop is closed.
+func (p *PodChecker) run(stop <-chan bool) {
+	defer p.RunWaitGroup.Done()
+	running := true
+	for running {
+		select {
+		case <-time.After(p.checkInterval()):
+			// disabling the pod checks may have stopped this run while it waited for the lock
+			p.settingsLock.RLock()
+			if !stopped(stop) {
+				p.processPodCheck()
+			}
+			p.settingsLock.RUnlock()
+		case <-stop:
+			running = false
+		}
+	}
+}
+
+func (p *PodChecker) enabled() bool {
+	p.settingsLock.RLock()
+	defer p.settingsLock.RUnlock()
+	return p.Enabled
+}
+
+func (p *PodChecker) checkInterval() time.Duration {
+	p.settingsLock.RLock()
+	defer p.settingsLock.RUnlock()
+	return p.CheckInterval
+}
+
+// reload replaces the pod check settings. The restart history of the containers is kept, and the
+// checker is started or stopped when the pod checks are enabled or disabled while running.
+func (p *PodChecker) reload(updated *PodChecker, running bool) {
+	p.settingsLock.Lock()
+	defer p.settingsLock.Unlock()
+	wasEnabled := p.Enabled
+	p.Enabled = updated.Enabled
+	p.CheckInterval = updated.CheckInterval
+	p.Threshold = updated.Threshold
+	p.RestartLimit = updated.RestartLimit
+	p.RestartWindow = updated.RestartWindow
+
+	switch {
+	case !running:
+	case p.Enabled && !wasEnabled:
+		p.start()
+	case !p.Enabled && wasEnabled:
+		logrus.Info("Stopping Pod Checker...")
+		p.stop()
+	}
+}
+
+func (p *PodChecker) processPodCheck() {
+	metrics.checkCycle(string(CheckGroupPod))
+	logrus.Debug("Running Pod Checks...")
+	pods, err := p.Pods()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to retrieve pods.")
+		return
+	}
+	if p.recorded == nil {
+		p.listRecorded()
+	}
+	restarts := make(map[string][]restartSample)
+	for _, pod := range pods {
+		for _, container := range pod.Status.ContainerStatuses {
+			name := fmt.Sprintf("%s/%s/%s", pod.Metadata.Namespace, pod.Metadata.Name, container.Name)
+			p.processContainerWaiting(pod, container, name, CheckTypePodCrashLoop, WaitingReasonCrashLoop)
+			p.processContainerWaiting(pod, container, name, CheckTypePodImagePull, WaitingReasonImagePull, WaitingReasonErrImagePull)
+			restarts[name] = p.processContainerRestarts(pod, container, name)
+		}
+	}
+	// only keep the restart history and the recorded checks of containers that still exist
+	p.restarts = restarts
+	for key, name := range p.recorded {
+		if _, exists := restarts[name]; !exists {
+			delete(p.recorded, key)
+		}
+	}
+	p.removeStaleChecks(CheckGroupPod, func(check KubeCheck) bool {
+		_, exists := restarts[check.Name]
+		return exists
+	}, "no longer exists", true)
+}
+
+func (p *PodChecker) processContainerWaiting(pod Pod, container ContainerStatus, name string, checkType KubeCheckType, reasons ...string) {
+	status := CheckStatusPass
+	message := name + " is not in " + reasons[0]
+	if waiting := container.State.Waiting; waiting != nil {
+		for _, reason := range reasons {
+			if waiting.Reason == reason {
+				status = CheckStatusFail
+				message = fmt.Sprintf("%s is in %s: %s", name, waiting.Reason, waiting.Message)
+			}
+		}
+	}
+
+	if !p.waitingTracker.held(string(checkType)+"/"+name, status, p.Threshold) {
+		return
+	}
+	p.processPodCheckResult(pod, name, checkType, status, message)
+}
+
+func (p *PodChecker) processContainerRestarts(pod Pod, container ContainerStatus, name string) []restartSample {
+	now := time.Now()
+	samples := append(p.restarts[name], restartSample{count: container.RestartCount, timestamp: now})
+	for len(samples) > 1 && now.Sub(samples[0].timestamp) > p.RestartWindow {
+		samples = samples[1:]
+	}
+
+	restarted := container.RestartCount - samples[0].count
+	status := CheckStatusPass
+	message := fmt.Sprintf("%s restarted %d times within %s", name, restarted, p.RestartWindow)
+	if p.RestartLimit > 0 && restarted >= p.RestartLimit {
+		status = CheckStatusFail
+	}
+	p.processPodCheckResult(pod, name, CheckTypePodRestarts, status, message)
+	return samples
+}
+
+func (p *PodChecker) processPodCheckResult(pod Pod, name string, checkType KubeCheckType, status CheckStatus, message string) {
+	check := KubeCheck{
+		Name:       name,
+		Node:       pod.Spec.NodeName,
+		CheckGroup: CheckGroupPod,
+		CheckType:  checkType,
+		Status:     status,
+		Message:    message,
+		Timestamp:  time.Now(),
+		Labels:     podLabels(pod),
+	}
+
+	// healthy containers are not recorded until they have failed at least once
+	key := checkKey(check.CheckGroup, check.CheckType, check.Name)
+	if status == CheckStatusPass && !p.isRecorded(key, check) {
+		return
+	}
+	p.processCheck(check)
+	if status != CheckStatusPass && p.recorded != nil {
+		p.recorded[key] = check.Name
+	}
+}
+
+// listRecorded lists the pod checks recorded in the KV store, so the passing containers are
+// not looked up one by one. It is retried on the next cycle if the checks cannot be listed.
+func (p *PodChecker) listRecorded() {
+	checks, err := p.listGroupChecks(CheckGroupPod)
+	if err != nil {
+		logrus.WithError(err).Warn("unable to list the recorded pod checks")
+		return
+	}
+	p.recorded = make(map[string]string, len(checks))
+	for _, check := range checks {
+		p.recorded[checkKey(check.CheckGroup, check.CheckType, check.Name)] = check.Name
+	}
+}
+
+// isRecorded returns true if the check is recorded in the KV store.
+func (p *PodChecker) isRecorded(key string, check KubeCheck) bool {
+	if p.recorded != nil {
+		_, ok := p.recorded[key]
+		return ok
+	}
+	exists, err := p.checkExists(check)
+	return err == nil && exists
+}
+
+func podLabels(pod Pod) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range pod.Metadata.Labels {
+		labels[k] = v
+	}
+	labels[LabelNamespace] = pod.Metadata.Namespace
+	return labels
+}
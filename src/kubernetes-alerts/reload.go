@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// ConfigReloader reloads the configuration on SIGHUP or when the config file changes. Pending
+// notifications and the check state in the KV store are kept.
+type ConfigReloader struct {
+	Settings     *Settings
+	Args         []string
+	RunWaitGroup sync.WaitGroup
+	stopChannel  chan bool
+	signals      chan os.Signal
+	modTime      time.Time
+}
+
+// catchSignals catches SIGHUP before the reloader is started, so a SIGHUP received while
+// kube-alerts is starting reloads the configuration once started instead of killing it.
+func (r *ConfigReloader) catchSignals() {
+	r.signals = make(chan os.Signal, 1)
+	signal.Notify(r.signals, syscall.SIGHUP)
+}
+
+func (r *ConfigReloader) start() {
+	logrus.Info("Starting Config Reloader...")
+	if r.signals == nil {
+		r.catchSignals()
+	}
+	r.stopChannel = make(chan bool)
+	r.RunWaitGroup.Add(1)
+	r.modTime = r.configModTime()
+	go r.run()
+}
+
+func (r *ConfigReloader) stop() {
+	close(r.stopChannel)
+}
+
+func (r *ConfigReloader) run() {
+	defer r.RunWaitGroup.Done()
+	defer signal.Stop(r.signals)
+
+	// the config file and watch interval are only read on start
+	var watch <-chan time.Time
+	if r.Settings.ConfigFile != "" && r.Settings.ConfigWatchInterval > 0 {
+		ticker := time.NewTicker(r.Settings.ConfigWatchInterval)
+		defer ticker.Stop()
+		watch = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.signals:
+			logrus.Info("Received SIGHUP, reloading configuration...")
+			r.modTime = r.configModTime()
+			r.reload()
+		case <-watch:
+			modTime := r.configModTime()
+			if modTime.Equal(r.modTime) {
+				continue
+			}
+			logrus.Infof("%s has changed, reloading configuration...", r.Settings.ConfigFile)
+			r.modTime = modTime
+			r.reload()
+		case <-r.stopChannel:
+			return
+		}
+	}
+}
+
+// reload loads the configuration again and applies it, keeping the current configuration if it is invalid.
+func (r *ConfigReloader) reload() {
+	updated, err := loadSettings(r.Args)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to reload configuration, keeping the current configuration.")
+		return
+	}
+	r.Settings.reload(updated)
+	logrus.Info("Configuration reloaded.")
+}
+
+func (r *ConfigReloader) configModTime() time.Time {
+	if r.Settings.ConfigFile == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(r.Settings.ConfigFile)
+	if err != nil {
+		logrus.WithError(err).Warnf("unable to stat %s", r.Settings.ConfigFile)
+		return r.modTime
+	}
+	return info.ModTime()
+}
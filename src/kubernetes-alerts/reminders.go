@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// RemindersKey holds when the unresolved checks were last sent to each notifier, so a restart or
+// a new leader does not remind them again right away.
+const RemindersKey = "kube-alerts-reminders"
+
+// sendReminders notifies again the checks that are still failing or warning once their reminder
+// interval has passed since they were last notified. Acknowledged, silenced and flapping checks
+// are skipped.
+func (n *NotifManager) sendReminders() {
+	if !n.remindersEnabled() {
+		// the reminders may have been disabled by a reload
+		if len(n.lastNotified) > 0 {
+			n.lastNotified = make(map[string]time.Time)
+		}
+		return
+	}
+	checks, err := n.listChecks()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to list checks for reminders")
+		return
+	}
+	silences, err := n.listSilences()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to list silences, skipping reminders")
+		return
+	}
+
+	now := time.Now()
+	unresolved := make(map[string]bool)
+	for _, notifier := range n.Notifiers {
+		if !notifier.NotifEnabled() {
+			continue
+		}
+		reminders := make([]KubeCheck, 0)
+		for _, check := range checks {
+			if check.Status == CheckStatusPass || check.Acknowledgement != nil || check.Flapping || silencedBy(silences, check, now) != nil {
+				continue
+			}
+			key := reminderKey(notifier, check)
+			unresolved[key] = true
+			interval := n.reminderInterval(notifier, check)
+			if interval <= 0 {
+				continue
+			}
+			last := check.Timestamp
+			if notified, ok := n.lastNotified[key]; ok && notified.After(last) {
+				last = notified
+			}
+			if now.Sub(last) < interval {
+				continue
+			}
+			check.Message = fmt.Sprintf("%s (%s for %s)", check.Message, statusVerb(check.Status), formatDuration(now.Sub(check.Timestamp)))
+			reminders = append(reminders, check)
+		}
+		if len(reminders) == 0 {
+			continue
+		}
+
+		logrus.Infof("Reminding %s of %d unresolved checks", notifier.NotifName(), len(reminders))
+		n.notify(notifier, reminders)
+		n.notified(notifier, reminders, now)
+	}
+
+	// forget the checks that have been resolved
+	for key := range n.lastNotified {
+		if !unresolved[key] {
+			delete(n.lastNotified, key)
+			n.notifiedChanged = true
+		}
+	}
+	if n.notifiedChanged {
+		if err := n.putValue(RemindersKey, n.lastNotified); err != nil {
+			logrus.WithError(err).Warn("Unable to save the reminder times")
+			return
+		}
+		n.notifiedChanged = false
+	}
+}
+
+// loadLastNotified loads when the unresolved checks were last sent to each notifier.
+func (n *NotifManager) loadLastNotified() {
+	var lastNotified map[string]time.Time
+	err := n.getValue(RemindersKey, &lastNotified)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logrus.WithError(err).Warn("Unable to load the reminder times, unresolved checks may be reminded early")
+		}
+		return
+	}
+	for key, at := range lastNotified {
+		n.lastNotified[key] = at
+	}
+}
+
+func (n *NotifManager) remindersEnabled() bool {
+	if n.RemindFailInterval > 0 || n.RemindWarnInterval > 0 {
+		return true
+	}
+	for _, route := range n.Routes {
+		if len(route.Reminders) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// notified records when the unresolved checks were last sent to the notifier, and forgets the
+// checks that pass. Nothing is recorded when the reminders are disabled.
+func (n *NotifManager) notified(notifier Notifier, checks []KubeCheck, at time.Time) {
+	if !n.remindersEnabled() {
+		return
+	}
+	for _, check := range checks {
+		key := reminderKey(notifier, check)
+		if check.Status == CheckStatusPass {
+			if _, ok := n.lastNotified[key]; ok {
+				delete(n.lastNotified, key)
+				n.notifiedChanged = true
+			}
+			continue
+		}
+		n.lastNotified[key] = at
+		n.notifiedChanged = true
+	}
+}
+
+// reminderInterval returns the interval between the reminders of a check for the notifier. The
+// reminders of the notifier's routes matching the check override the default interval of the
+// check status, the shortest interval is used. 0 disables the reminders.
+func (n *NotifManager) reminderInterval(notifier Notifier, check KubeCheck) time.Duration {
+	interval := n.RemindFailInterval
+	if check.Status == CheckStatusWarn {
+		interval = n.RemindWarnInterval
+	}
+
+	routed, matched, overridden := false, false, false
+	for _, route := range n.Routes {
+		if !route.hasNotifier(notifier) {
+			continue
+		}
+		routed = true
+		if !route.matches(check) {
+			continue
+		}
+		matched = true
+		remind, ok := route.Reminders[check.Status]
+		if !ok {
+			continue
+		}
+		if !overridden || (remind > 0 && (interval <= 0 || remind < interval)) {
+			interval = remind
+		}
+		overridden = true
+	}
+	if routed && !matched {
+		// the check is not routed to the notifier
+		return 0
+	}
+	return interval
+}
+
+func reminderKey(notifier Notifier, check KubeCheck) string {
+	return notifier.NotifName() + "/" + checkKey(check.CheckGroup, check.CheckType, check.Name)
+}
+
+// parseReminders parses the reminder intervals of a route, e.g. fail=3600,warn=14400 (seconds).
+func parseReminders(values []string) (map[CheckStatus]time.Duration, error) {
+	reminders := make(map[CheckStatus]time.Duration)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid route reminder %q, expected status=seconds", value)
+		}
+		status := CheckStatus(parts[0])
+		if status != CheckStatusFail && status != CheckStatusWarn {
+			return nil, fmt.Errorf("invalid route reminder status %q, expected fail or warn", parts[0])
+		}
+		seconds, err := strconv.Atoi(parts[1])
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid route reminder interval %q, expected seconds", parts[1])
+		}
+		reminders[status] = time.Duration(seconds) * time.Second
+	}
+	return reminders, nil
+}
+
+func statusVerb(status CheckStatus) string {
+	switch status {
+	case CheckStatusFail:
+		return "failing"
+	case CheckStatusWarn:
+		return "warning"
+	default:
+		return string(status)
+	}
+}
+
+// formatDuration formats a duration in days, hours and minutes, e.g. 2h15m.
+func formatDuration(d time.Duration) string {
+	d = d / time.Minute * time.Minute
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	minutes := (d - hours*time.Hour) / time.Minute
+
+	formatted := ""
+	if days > 0 {
+		formatted += fmt.Sprintf("%dd", days)
+	}
+	if hours > 0 {
+		formatted += fmt.Sprintf("%dh", hours)
+	}
+	if minutes > 0 || formatted == "" {
+		formatted += fmt.Sprintf("%dm", minutes)
+	}
+	return formatted
+}
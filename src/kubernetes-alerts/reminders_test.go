@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReminders(t *testing.T) {
+	tests := []struct {
+		values   []string
+		expected map[CheckStatus]time.Duration
+		err      bool
+	}{
+		{[]string{}, map[CheckStatus]time.Duration{}, false},
+		{[]string{"fail=3600"}, map[CheckStatus]time.Duration{CheckStatusFail: time.Hour}, false},
+		{
+			[]string{"fail=900", "warn=0"},
+			map[CheckStatus]time.Duration{CheckStatusFail: 15 * time.Minute, CheckStatusWarn: 0},
+			false,
+		},
+		{[]string{"fail"}, nil, true},
+		{[]string{"pass=60"}, nil, true},
+		{[]string{"fail=1h"}, nil, true},
+		{[]string{"warn=-60"}, nil, true},
+	}
+	for _, test := range tests {
+		reminders, err := parseReminders(test.values)
+		if test.err {
+			assert.Error(t, err, test.values)
+			continue
+		}
+		assert.NoError(t, err, test.values)
+		assert.Equal(t, test.expected, reminders, test.values)
+	}
+}
+
+func TestParseRouteReminders(t *testing.T) {
+	slack := &SlackNotifier{}
+	route, err := parseNotifRoute("notifiers=slack;reminders=fail=600,warn=3600", map[string]Notifier{"slack": slack})
+	assert.NoError(t, err)
+	assert.Equal(t, map[CheckStatus]time.Duration{CheckStatusFail: 10 * time.Minute, CheckStatusWarn: time.Hour}, route.Reminders)
+
+	_, err = parseNotifRoute("notifiers=slack;reminders=pass=600", map[string]Notifier{"slack": slack})
+	assert.Error(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0m"},
+		{30 * time.Second, "0m"},
+		{time.Minute, "1m"},
+		{59*time.Minute + 59*time.Second, "59m"},
+		{time.Hour, "1h"},
+		{2*time.Hour + 15*time.Minute, "2h15m"},
+		{24 * time.Hour, "1d"},
+		{50*time.Hour + 3*time.Minute, "2d2h3m"},
+		{48*time.Hour + 5*time.Minute, "2d5m"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatDuration(test.duration), test.duration.String())
+	}
+}
+
+func TestStatusVerb(t *testing.T) {
+	assert.Equal(t, "failing", statusVerb(CheckStatusFail))
+	assert.Equal(t, "warning", statusVerb(CheckStatusWarn))
+	assert.Equal(t, "pass", statusVerb(CheckStatusPass))
+}
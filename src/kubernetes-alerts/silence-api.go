@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+)
+
+// silences lists the silences or creates one, e.g.
+// POST /api/silences {"nodes": ["node-1"], "end": "2016-06-01T18:00:00Z", "createdBy": "jane", "comment": "kernel upgrade"}
+func (h *HttpServer) silences(w http.ResponseWriter, r *http.Request) {
+	if !h.allowApi(w, r, "GET", "POST") {
+		return
+	}
+	if r.Method == "GET" {
+		silences, err := h.KVClient.listSilences()
+		if err != nil {
+			logrus.WithError(err).Error("unable to list silences")
+			writeError(w, http.StatusInternalServerError, "unable to list silences")
+			return
+		}
+		writeJson(w, http.StatusOK, silences)
+		return
+	}
+
+	var silence Silence
+	if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid silence: "+err.Error())
+		return
+	}
+	now := time.Now()
+	silence.ID = fmt.Sprintf("%d", now.UnixNano())
+	if silence.Start.IsZero() {
+		silence.Start = now
+	}
+	if err := silence.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.KVClient.saveSilence(silence); err != nil {
+		logrus.WithError(err).Error("unable to save silence")
+		writeError(w, http.StatusInternalServerError, "unable to save silence")
+		return
+	}
+	logrus.Infof("Silence %s created by %s until %s", silence.ID, silence.CreatedBy, silence.End)
+	writeJson(w, http.StatusCreated, silence)
+}
+
+// silence gets a silence or expires it, e.g. DELETE /api/silences/<id>. Expired silences are
+// removed on the next notification interval, notifying the checks that are still failing.
+func (h *HttpServer) silence(w http.ResponseWriter, r *http.Request) {
+	if !h.allowApi(w, r, "GET", "DELETE") {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/silences/")
+	var silence Silence
+	err := h.KVClient.getValue(silenceKey(id), &silence)
+	if err == store.ErrKeyNotFound || id == "" {
+		writeError(w, http.StatusNotFound, "silence not found")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("unable to get silence")
+		writeError(w, http.StatusInternalServerError, "unable to get silence")
+		return
+	}
+
+	if r.Method == "DELETE" {
+		now := time.Now()
+		if silence.End.After(now) {
+			silence.End = now
+		}
+		if err := h.KVClient.saveSilence(silence); err != nil {
+			logrus.WithError(err).Error("unable to expire silence")
+			writeError(w, http.StatusInternalServerError, "unable to expire silence")
+			return
+		}
+		logrus.Infof("Silence %s expired", silence.ID)
+	}
+	writeJson(w, http.StatusOK, silence)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const SilencePrefix = "kube-alerts-silences"
+
+// Silence suppresses the notifications of the checks it matches between Start and End. Checks
+// are still recorded while silenced. An empty matcher matches every check.
+type Silence struct {
+	ID        string            `json:"id"`
+	Nodes     []string          `json:"nodes,omitempty"`
+	Groups    []string          `json:"groups,omitempty"`
+	Types     []string          `json:"types,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	CreatedBy string            `json:"createdBy"`
+	Comment   string            `json:"comment"`
+}
+
+func silenceKey(id string) string {
+	return fmt.Sprintf("%s/%s", SilencePrefix, id)
+}
+
+func (s *Silence) validate() error {
+	switch {
+	case len(s.Nodes) == 0 && len(s.Groups) == 0 && len(s.Types) == 0 && len(s.Labels) == 0:
+		return errors.New("a silence needs at least one node, group, type or label matcher")
+	case s.End.IsZero():
+		return errors.New("a silence needs an end")
+	case !s.End.After(s.Start):
+		return errors.New("a silence must end after its start")
+	case s.CreatedBy == "":
+		return errors.New("a silence needs a creator")
+	}
+	return nil
+}
+
+func (s *Silence) active(now time.Time) bool {
+	return !now.Before(s.Start) && now.Before(s.End)
+}
+
+func (s *Silence) expired(now time.Time) bool {
+	return !now.Before(s.End)
+}
+
+func (s *Silence) matches(check KubeCheck) bool {
+	if !matchesAny(s.Nodes, check.Node) || !matchesAny(s.Groups, string(check.CheckGroup)) || !matchesAny(s.Types, string(check.CheckType)) {
+		return false
+	}
+	for name, value := range s.Labels {
+		if check.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (kvc *KVClient) listSilences() ([]Silence, error) {
+	kvpairs, err := kvc.listValues(SilencePrefix)
+	if err != nil {
+		return nil, err
+	}
+	silences := make([]Silence, 0, len(kvpairs))
+	for _, kvpair := range kvpairs {
+		var silence Silence
+		if err := json.Unmarshal(kvpair.Value, &silence); err != nil {
+			logrus.WithError(err).Warnf("unable to unmarshal silence %s", kvpair.Key)
+			continue
+		}
+		silences = append(silences, silence)
+	}
+	return silences, nil
+}
+
+func (kvc *KVClient) saveSilence(silence Silence) error {
+	return kvc.putValue(silenceKey(silence.ID), silence)
+}
+
+// silencedBy returns the active silence matching the check, if any.
+func silencedBy(silences []Silence, check KubeCheck, now time.Time) *Silence {
+	for i := range silences {
+		if silences[i].active(now) && silences[i].matches(check) {
+			return &silences[i]
+		}
+	}
+	return nil
+}
+
+// removeSilenced drops the checks matching an active silence. Checks are kept if the silences
+// can't be read, so nothing is suppressed by mistake.
+func (n *NotifManager) removeSilenced(checks []KubeCheck) []KubeCheck {
+	silences, err := n.listSilences()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to list silences, notifying every check")
+		return checks
+	}
+	now := time.Now()
+	unsilenced := make([]KubeCheck, 0, len(checks))
+	for _, check := range checks {
+		if silence := silencedBy(silences, check, now); silence != nil {
+			logrus.Infof("check %s is silenced by %s until %s", check.Name, silence.ID, silence.End)
+			continue
+		}
+		unsilenced = append(unsilenced, check)
+	}
+	return unsilenced
+}
+
+// expireSilences removes the silences that have ended and notifies the checks they matched
+// that are still failing and not acknowledged.
+func (n *NotifManager) expireSilences() {
+	silences, err := n.listSilences()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to list silences")
+		return
+	}
+	now := time.Now()
+	var checks []KubeCheck
+	notified := make(map[string]bool)
+	for _, silence := range silences {
+		if !silence.expired(now) {
+			continue
+		}
+		if checks == nil {
+			if checks, err = n.listChecks(); err != nil {
+				logrus.WithError(err).Error("Unable to list checks of expired silences")
+				return
+			}
+		}
+		logrus.Infof("Silence %s has expired", silence.ID)
+		for _, check := range checks {
+			key := checkKey(check.CheckGroup, check.CheckType, check.Name)
+			if notified[key] || check.Status == CheckStatusPass || check.Acknowledgement != nil || !silence.matches(check) || silencedBy(silences, check, now) != nil {
+				continue
+			}
+			notified[key] = true
+			logrus.Infof("check %s is still %s after silence %s, will notify", check.Name, check.Status, silence.ID)
+			n.checks = append(n.checks, check)
+		}
+		if err := n.deleteKey(silenceKey(silence.ID)); err != nil {
+			logrus.WithError(err).Errorf("Unable to remove expired silence %s", silence.ID)
+		}
+	}
+}
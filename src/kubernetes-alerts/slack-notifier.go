@@ -13,6 +13,7 @@ import (
 )
 
 type SlackNotifier struct {
+	Name        string       `json:"-"`
 	Enabled     bool         `json:"-"`
 	ClusterName string       `json:"-"`
 	Url         string       `json:"-"`
@@ -23,14 +24,31 @@ type SlackNotifier struct {
 	Text        string       `json:"text,omitempty"`
 	Attachments []attachment `json:"attachments,omitempty"`
 	Detailed    bool         `json:"-"`
+
+	// VerificationToken enables the acknowledge buttons, it must match the token of the Slack app
+	VerificationToken string `json:"-"`
 }
 
+const (
+	SlackAckCallback = "kube-alerts-ack"
+	SlackMaxActions  = 20
+)
+
 type attachment struct {
-	Color    string   `json:"color"`
-	Title    string   `json:"title"`
-	Pretext  string   `json:"pretext"`
-	Text     string   `json:"text"`
-	MrkdwnIn []string `json:"mrkdwn_in"`
+	Color      string   `json:"color"`
+	Title      string   `json:"title"`
+	Pretext    string   `json:"pretext"`
+	Text       string   `json:"text"`
+	MrkdwnIn   []string `json:"mrkdwn_in"`
+	CallbackId string   `json:"callback_id,omitempty"`
+	Actions    []action `json:"actions,omitempty"`
+}
+
+type action struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
 func (slack *SlackNotifier) Notify(checks []KubeCheck) bool {
@@ -48,6 +66,10 @@ func (slack *SlackNotifier) NotifEnabled() bool {
 	return slack.Enabled
 }
 
+func (slack *SlackNotifier) NotifName() string {
+	return slack.Name
+}
+
 func (slack *SlackNotifier) notifySimple(checks []KubeCheck) bool {
 
 	_, pass, warn, fail := NotifSummary(checks)
@@ -63,7 +85,7 @@ func (slack *SlackNotifier) notifySimple(checks []KubeCheck) bool {
 	detailTemplate := ` [%s] %s: %s.\n`
 	var details string
 	for _, check := range checks {
-		details += fmt.Sprintf(detailTemplate, strings.ToUpper(string(check.Status)), check.Timestamp.String(), check.Message)
+		details += fmt.Sprintf(detailTemplate, strings.ToUpper(string(check.Status)), check.Timestamp.String(), checkMessage(check))
 	}
 
 	text := fmt.Sprintf(textTemplate, slack.ClusterName, pass, warn, fail, details)
@@ -113,7 +135,7 @@ func (slack *SlackNotifier) notifyDetailed(checks []KubeCheck) bool {
 		case CheckStatusFail:
 			statusEmoji = ":rage:"
 		}
-		details += fmt.Sprintf(detailTemplate, statusEmoji, check.Timestamp.String(), check.Message)
+		details += fmt.Sprintf(detailTemplate, statusEmoji, check.Timestamp.String(), checkMessage(check))
 		details += "\n"
 	}
 
@@ -124,12 +146,40 @@ func (slack *SlackNotifier) notifyDetailed(checks []KubeCheck) bool {
 		Text:     details,
 		MrkdwnIn: []string{"text", "pretext"},
 	}
-	slack.Attachments = []attachment{a}
+	slack.Attachments = append([]attachment{a}, slack.ackAttachments(checks)...)
 
 	return slack.postToSlack()
 
 }
 
+// ackAttachments returns an attachment with an acknowledge button for each failing check that
+// is not acknowledged yet, when the buttons are enabled.
+func (slack *SlackNotifier) ackAttachments(checks []KubeCheck) []attachment {
+	attachments := make([]attachment, 0)
+	if slack.VerificationToken == "" {
+		return attachments
+	}
+	for _, check := range checks {
+		if check.Status == CheckStatusPass || check.Acknowledgement != nil {
+			continue
+		}
+		if len(attachments) == SlackMaxActions {
+			break
+		}
+		attachments = append(attachments, attachment{
+			Text:       check.Message,
+			CallbackId: SlackAckCallback,
+			Actions: []action{{
+				Name:  "ack",
+				Text:  "Acknowledge",
+				Type:  "button",
+				Value: checkKey(check.CheckGroup, check.CheckType, check.Name),
+			}},
+		})
+	}
+	return attachments
+}
+
 func (slack *SlackNotifier) postToSlack() bool {
 
 	data, err := json.Marshal(slack)
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// removeStaleChecks deletes the recorded checks of a group whose object no longer exists, e.g.
+// the checks of a deleted node. When notify and NotifyRemoved are set, a stale check that is not
+// passing is notified as passing with the removed message first, so its alert is resolved.
+func (c *CheckProcessor) removeStaleChecks(checkGroup KubeCheckGroup, exists func(KubeCheck) bool, removed string, notify bool) {
+	c.settingsLock.RLock()
+	defer c.settingsLock.RUnlock()
+	checks, err := c.listGroupChecks(checkGroup)
+	if err != nil {
+		logrus.WithError(err).Warnf("unable to list the %s checks", checkGroup)
+		return
+	}
+	for _, check := range checks {
+		if exists(check) {
+			continue
+		}
+		if notify && c.NotifyRemoved && check.Status != CheckStatusPass {
+			logrus.Infof("check %s is %s, will notify", check.Name, removed)
+			check.Status = CheckStatusPass
+			check.Message = fmt.Sprintf("%s %s", check.Name, removed)
+			check.Timestamp = time.Now()
+			check.Acknowledgement = nil
+			check.Flapping = false
+			c.addNotification(check)
+		}
+		logrus.Infof("removing check %s, %s", check.Name, removed)
+		if err := c.deleteKey(checkKey(check.CheckGroup, check.CheckType, check.Name)); err != nil {
+			logrus.WithError(err).Warnf("unable to remove check %s", check.Name)
+			continue
+		}
+		if err := c.deleteKey(transitionKey(check)); err != nil {
+			logrus.WithError(err).Warnf("unable to remove the transitions of check %s", check.Name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/libkv/store"
+)
+
+const (
+	MemoryBackend    store.Backend = "memory"
+	ConfigMapBackend store.Backend = "configmap"
+)
+
+// StateStore is the part of a libkv store kube-alerts keeps its state in. Every libkv store is a
+// StateStore, MemoryStore and ConfigMapStore keep the state without a KV store.
+type StateStore interface {
+	Put(key string, value []byte, options *store.WriteOptions) error
+	Get(key string) (*store.KVPair, error)
+	Delete(key string) error
+	Exists(key string) (bool, error)
+	List(directory string) ([]*store.KVPair, error)
+	AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error)
+	NewLock(key string, options *store.LockOptions) (store.Locker, error)
+	Close()
+}
+
+// MemoryStore keeps the state in memory, it is lost when kube-alerts restarts. Like consul and
+// boltdb, List returns every key below the directory.
+type MemoryStore struct {
+	lock  sync.RWMutex
+	pairs map[string]*store.KVPair
+	index uint64
+}
+
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{pairs: make(map[string]*store.KVPair)}
+}
+
+func normalizeKey(key string) string {
+	return strings.TrimPrefix(key, "/")
+}
+
+func (m *MemoryStore) Put(key string, value []byte, options *store.WriteOptions) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.put(normalizeKey(key), value)
+	return nil
+}
+
+func (m *MemoryStore) put(key string, value []byte) *store.KVPair {
+	m.index++
+	pair := &store.KVPair{Key: key, Value: value, LastIndex: m.index}
+	m.pairs[key] = pair
+	return pair
+}
+
+func (m *MemoryStore) Get(key string) (*store.KVPair, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	pair, ok := m.pairs[normalizeKey(key)]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+	return pair, nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key = normalizeKey(key)
+	if _, ok := m.pairs[key]; !ok {
+		return store.ErrKeyNotFound
+	}
+	delete(m.pairs, key)
+	return nil
+}
+
+func (m *MemoryStore) Exists(key string) (bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, ok := m.pairs[normalizeKey(key)]
+	return ok, nil
+}
+
+func (m *MemoryStore) List(directory string) ([]*store.KVPair, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	directory = normalizeKey(directory)
+	pairs := make([]*store.KVPair, 0)
+	for key, pair := range m.pairs {
+		if strings.HasPrefix(key, directory) {
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+	sort.Sort(byPairKey(pairs))
+	return pairs, nil
+}
+
+// AtomicPut puts the value if the key was not modified since previous was read, or if the key
+// does not exist when previous is nil.
+func (m *MemoryStore) AtomicPut(key string, value []byte, previous *store.KVPair, options *store.WriteOptions) (bool, *store.KVPair, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key = normalizeKey(key)
+	current, exists := m.pairs[key]
+	switch {
+	case previous == nil && exists:
+		return false, nil, store.ErrKeyExists
+	case previous != nil && !exists:
+		return false, nil, store.ErrKeyNotFound
+	case previous != nil && current.LastIndex != previous.LastIndex:
+		return false, nil, store.ErrKeyModified
+	}
+	return true, m.put(key, value), nil
+}
+
+// NewLock is not supported, the state of a memory store is not shared with other replicas.
+func (m *MemoryStore) NewLock(key string, options *store.LockOptions) (store.Locker, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+func (m *MemoryStore) Close() {}
+
+// values returns a copy of every key and value below the prefixes.
+func (m *MemoryStore) values(prefixes []string) map[string]string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	values := make(map[string]string)
+	for key, pair := range m.pairs {
+		if hasAnyPrefix(key, prefixes) {
+			values[key] = string(pair.Value)
+		}
+	}
+	return values
+}
+
+// reset replaces every key and value below the prefixes.
+func (m *MemoryStore) reset(prefixes []string, values map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for key := range m.pairs {
+		if hasAnyPrefix(key, prefixes) {
+			delete(m.pairs, key)
+		}
+	}
+	for key, value := range values {
+		m.put(key, []byte(value))
+	}
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// byPairKey sorts key/value pairs by key.
+type byPairKey []*store.KVPair
+
+func (p byPairKey) Len() int           { return len(p) }
+func (p byPairKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byPairKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
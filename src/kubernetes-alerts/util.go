@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"encoding/json"
@@ -15,6 +17,37 @@ func toTime(str string) (time.Time, error) {
 	return time.Parse(layout, str)
 }
 
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func containsString(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectStrings returns the values that are also in others.
+func intersectStrings(values, others []string) []string {
+	intersection := make([]string, 0, len(values))
+	for _, value := range values {
+		if containsString(value, others) {
+			intersection = append(intersection, value)
+		}
+	}
+	return intersection
+}
+
 func toReader(data interface{}) (io.Reader, error) {
 	b, err := json.Marshal(data)
 	if err != nil {
@@ -23,3 +56,70 @@ func toReader(data interface{}) (io.Reader, error) {
 	}
 	return bytes.NewReader(b), nil
 }
+
+// parseCpuQuantity converts a kubernetes cpu quantity (e.g. "4", "3500m") to millicores.
+func parseCpuQuantity(quantity string) (uint64, error) {
+	if strings.HasSuffix(quantity, "m") {
+		return strconv.ParseUint(strings.TrimSuffix(quantity, "m"), 10, 64)
+	}
+	cores, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(cores * 1000), nil
+}
+
+var memorySuffixes = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// parseMemoryQuantity converts a kubernetes memory quantity (e.g. "16432328Ki", "2Gi") to bytes.
+func parseMemoryQuantity(quantity string) (uint64, error) {
+	for _, s := range memorySuffixes {
+		if strings.HasSuffix(quantity, s.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(quantity, s.suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return uint64(value * float64(s.multiplier)), nil
+		}
+	}
+	value, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(value), nil
+}
+
+func percentOf(value, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+// stopped returns true if the stop channel has been closed. The checkers pass the stop channel
+// of a run to it rather than reading their current channel, as a checker may be started again,
+// by a reload or when the leadership is regained, before its previous run has returned.
+func stopped(stop <-chan bool) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCpuQuantity(t *testing.T) {
+	tests := []struct {
+		quantity string
+		expected uint64
+		err      bool
+	}{
+		{"4", 4000, false},
+		{"0.5", 500, false},
+		{"3500m", 3500, false},
+		{"0m", 0, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1.5m", 0, true},
+	}
+	for _, test := range tests {
+		millicores, err := parseCpuQuantity(test.quantity)
+		if test.err {
+			assert.Error(t, err, test.quantity)
+			continue
+		}
+		assert.NoError(t, err, test.quantity)
+		assert.Equal(t, test.expected, millicores, test.quantity)
+	}
+}
+
+func TestParseMemoryQuantity(t *testing.T) {
+	tests := []struct {
+		quantity string
+		expected uint64
+		err      bool
+	}{
+		{"1024", 1024, false},
+		{"16432328Ki", 16432328 << 10, false},
+		{"2Gi", 2 << 30, false},
+		{"1.5Mi", 3 << 19, false},
+		{"1Ti", 1 << 40, false},
+		{"128M", 128e6, false},
+		{"2G", 2e9, false},
+		{"1k", 1000, false},
+		{"", 0, true},
+		{"Gi", 0, true},
+		{"2Xi", 0, true},
+	}
+	for _, test := range tests {
+		bytes, err := parseMemoryQuantity(test.quantity)
+		if test.err {
+			assert.Error(t, err, test.quantity)
+			continue
+		}
+		assert.NoError(t, err, test.quantity)
+		assert.Equal(t, test.expected, bytes, test.quantity)
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		value    uint64
+		total    uint64
+		expected float64
+	}{
+		{50, 200, 25},
+		{200, 200, 100},
+		{0, 200, 0},
+		{50, 0, 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, percentOf(test.value, test.total))
+	}
+}
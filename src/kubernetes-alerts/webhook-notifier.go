@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	WebhookSignatureHeader = "X-Kube-Alerts-Signature"
+	WebhookTimeout         = 10 * time.Second
+)
+
+var webhookClient = &http.Client{Timeout: WebhookTimeout}
+
+type WebhookNotifier struct {
+	Name        string
+	Enabled     bool
+	ClusterName string
+	Urls        []string
+	Headers     map[string]string
+	Template    string
+	Secret      string
+}
+
+type WebhookData struct {
+	ClusterName  string
+	SystemStatus string
+	FailCount    int
+	WarnCount    int
+	PassCount    int
+	Checks       []KubeCheck
+}
+
+func (webhook *WebhookNotifier) Notify(checks []KubeCheck) bool {
+	return len(webhook.NotifyUrls(checks, webhook.Urls)) == 0
+}
+
+// NotifyUrls posts the checks to each of the urls and returns the urls that failed.
+func (webhook *WebhookNotifier) NotifyUrls(checks []KubeCheck, urls []string) []string {
+	logrus.Infof("Sending %d notifications to %d webhooks", len(checks), len(urls))
+
+	body, err := webhook.body(checks)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to create webhook payload")
+		return urls
+	}
+
+	failed := make([]string, 0)
+	for _, url := range urls {
+		if err := webhook.post(url, body); err != nil {
+			logrus.WithError(err).Errorf("Unable to notify webhook %s", url)
+			failed = append(failed, url)
+		}
+	}
+	if len(failed) == 0 {
+		logrus.Info("Webhook notifications sent.")
+	}
+	return failed
+}
+
+func (webhook *WebhookNotifier) NotifUrls() []string {
+	return webhook.Urls
+}
+
+func (webhook *WebhookNotifier) NotifEnabled() bool {
+	return webhook.Enabled
+}
+
+func (webhook *WebhookNotifier) NotifName() string {
+	return webhook.Name
+}
+
+// body returns the checks as a JSON array, or the output of the template when one is configured.
+func (webhook *WebhookNotifier) body(checks []KubeCheck) ([]byte, error) {
+	if webhook.Template == "" {
+		return json.Marshal(checks)
+	}
+
+	// ParseFiles names the template after the base name of the file
+	name := filepath.Base(webhook.Template)
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"json": toJson}).ParseFiles(webhook.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	overall, pass, warn, fail := NotifSummary(checks)
+	data := WebhookData{
+		ClusterName:  webhook.ClusterName,
+		SystemStatus: string(overall),
+		FailCount:    fail,
+		WarnCount:    warn,
+		PassCount:    pass,
+		Checks:       checks,
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func (webhook *WebhookNotifier) post(url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signPayload(webhook.Secret, body))
+	}
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		response, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, string(response))
+	}
+	return nil
+}
+
+// signPayload returns the hex encoded HMAC-SHA256 of the payload.
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func toJson(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// parseHeaders parses a comma separated list of Name:Value headers.
+func parseHeaders(headers string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, header := range splitList(headers) {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header %q, expected Name:Value", header)
+		}
+		parsed[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return parsed, nil
+}